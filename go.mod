@@ -21,6 +21,7 @@ require (
 	github.com/jimschubert/answer v0.1.5
 	github.com/k0kubun/pp/v3 v3.4.1
 	github.com/k1LoW/duration v1.2.0
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-isatty v0.0.20
 	github.com/moby/sys/mountinfo v0.7.2
 	github.com/muesli/reflow v0.3.0
@@ -30,6 +31,7 @@ require (
 	github.com/rs/xid v1.6.0
 	github.com/samber/lo v1.49.1
 	golang.org/x/sync v0.11.0
+	golang.org/x/sys v0.30.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -56,7 +58,6 @@ require (
 	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/exp v0.0.0-20240909161429-701f63a606c0 // indirect
 	golang.org/x/net v0.34.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 )
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// rmGroup is the group of the rm compatible options
+const rmGroup = "Compatible (rm) Options"
+
+// newParser returns the parser of the command line into opt.
+// Subcommands are optional so that "gomi files..." keeps working like rm.
+func newParser(opt *Option, name string) *flags.Parser {
+	parser := flags.NewParser(opt, flags.Default)
+	parser.Name = name
+	parser.Usage = "[-b | files...]"
+	parser.SubcommandsOptional = true
+	return parser
+}
+
+// mutatingCommands are the subcommands changing the trash or the files of the user,
+// which are never run unless they are given in an explicit form (see fileArgs)
+var mutatingCommands = []string{"undo", "restore", "import", "import-archive", "empty", "migrate"}
+
+// fileArgs returns args with "--" inserted before the first argument if it names a
+// subcommand but is meant as a file, so that gomi used as rm never runs a subcommand
+// in place of moving a file to trash. It is meant as a file if rm options are given
+// before it, as in "rm -rf undo", or if a file of that name exists.
+// A subcommand is still run with a file of its name in the current directory
+// by giving options after it, and such a file is trashed by passing it after "--".
+//
+// Without a file of its name, "rm undo" cannot be told from "gomi undo", so the
+// subcommands in mutatingCommands also need an option after them, "--" at least,
+// as in "gomi undo --". An error with that hint is returned otherwise.
+func fileArgs(parser *flags.Parser, args []string) ([]string, error) {
+	rm := parser.Group.Find(rmGroup)
+	var rmOptions bool
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args, nil
+
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			option := parser.FindOptionByLongName(name)
+			if option == nil {
+				// The parser reports unknown options
+				continue
+			}
+			if rm != nil && rm.FindOptionByLongName(name) != nil {
+				rmOptions = true
+			}
+			if !hasValue && takesValue(option) {
+				i++
+			}
+
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			for j, r := range arg[1:] {
+				option := parser.FindOptionByShortName(r)
+				if option == nil {
+					break
+				}
+				if rm != nil && rm.FindOptionByShortName(r) != nil {
+					rmOptions = true
+				}
+				if takesValue(option) {
+					// The value is the rest of the argument or the next one
+					if j == len(arg)-2 {
+						i++
+					}
+					break
+				}
+			}
+
+		default:
+			if parser.Find(arg) == nil {
+				return args, nil
+			}
+			if _, err := os.Lstat(arg); rmOptions || err == nil {
+				return slices.Insert(slices.Clone(args), i, "--"), nil
+			}
+			rest := args[i+1:]
+			if slices.Contains(mutatingCommands, arg) && !slices.ContainsFunc(rest, isOption) {
+				explicit := append([]string{parser.Name, arg, "--"}, rest...)
+				return nil, fmt.Errorf("%s: no such file, run %q to use the %s command", arg, strings.Join(explicit, " "), arg)
+			}
+			return args, nil
+		}
+	}
+	return args, nil
+}
+
+// isOption reports whether the argument is an option or "--"
+func isOption(arg string) bool {
+	return strings.HasPrefix(arg, "-") && arg != "-"
+}
+
+// takesValue reports whether the option requires a value
+func takesValue(option *flags.Option) bool {
+	return option.Field().Type.Kind() != reflect.Bool && !option.OptionalArgument
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileArgs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.WriteFile("list", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    string
+		want    string
+		wantErr bool
+	}{
+		// "rm undo" and "gomi undo" are the same, so mutating subcommands need an option after them
+		{"undo", "", true},
+		{"restore x", "", true},
+		{"import p", "", true},
+		{"import-archive a.tar.zst", "", true},
+		{"empty", "", true},
+		{"migrate", "", true},
+		{"undo --", "undo --", false},
+		{"undo --run id", "undo --run id", false},
+		{"restore -- x", "restore -- x", false},
+		{"restore x --all-matches", "restore x --all-matches", false},
+		{"empty -", "", true},
+		{"stats", "stats", false},
+		{"export -o a.tar.zst", "export -o a.tar.zst", false},
+		{"-rf undo", "-rf -- undo", false},
+		{"-r --force export", "-r --force -- export", false},
+		{"--interactive=once stats", "--interactive=once -- stats", false},
+		{"list", "-- list", false},
+		{"--config x stats", "--config x stats", false},
+		{"--config=x -V stats", "--config=x -V stats", false},
+		{"--debug empty --all", "--debug empty --all", false},
+		{"-- empty", "-- empty", false},
+		{"-rf file undo", "-rf file undo", false},
+		{"-f undo", "-f -- undo", false},
+		{"-f", "-f", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			var opt Option
+			parser := newParser(&opt, "gomi")
+			got, err := fileArgs(parser, strings.Fields(tt.args))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fileArgs(%q) returned error %v, want error %v", tt.args, err, tt.wantErr)
+			}
+			if want := strings.Fields(tt.want); !slices.Equal(got, want) {
+				t.Errorf("fileArgs(%q) = %q, want %q", tt.args, got, want)
+			}
+		})
+	}
+}
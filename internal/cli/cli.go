@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,8 +26,28 @@ type Option struct {
 	Restore bool   `short:"b" long:"restore" description:"Restore deleted file"`
 	Config  string `long:"config" description:"Path to config file" default:""`
 
+	TrashAnyway bool   `long:"trash-anyway" description:"Move files to trash even if they match core.direct_delete"`
+	OnConflict  string `long:"on-conflict" description:"What to do when restoring onto an existing path (default: core.restore.on_conflict)" choice:"prompt" choice:"rename" choice:"overwrite" choice:"skip" choice:"merge"`
+
+	IgnoreChecksum bool `long:"ignore-checksum" description:"Restore files without verifying their checksums"`
+
 	Meta MetaOption `group:"Meta Options"`
 	Rm   RmOption   `group:"Compatible (rm) Options"`
+
+	Empty EmptyCommand `command:"empty" description:"Permanently delete files in the trash"`
+	List  ListCommand  `command:"list" description:"List files in the trash"`
+
+	RestoreCmd RestoreCommand `command:"restore" description:"Restore files matching the given paths, globs or IDs"`
+	Undo       UndoCommand    `command:"undo" description:"Restore every file trashed by the most recent run"`
+	ConfigCmd  ConfigCommand  `command:"config" description:"Inspect the configuration"`
+	Doctor     DoctorCommand  `command:"doctor" description:"Find and repair inconsistencies in the trash"`
+	Migrate    MigrateCommand `command:"migrate" description:"Move every file of the legacy trash (~/.gomi) to another storage"`
+	Import     ImportCommand  `command:"import" description:"Move every file of another trash directory (e.g. trash-cli or an old drive) into the trash"`
+
+	Export        ExportCommand        `command:"export" description:"Pack files in the trash into a portable archive"`
+	ImportArchive ImportArchiveCommand `command:"import-archive" description:"Put files of an archive written by \"gomi export\" back into the trash"`
+	Verify        VerifyCommand        `command:"verify" description:"Check that files in the trash are unchanged since they were trashed"`
+	Stats         StatsCommand         `command:"stats" description:"Show how many files the trash holds and the space they take"`
 }
 
 type MetaOption struct {
@@ -37,20 +58,32 @@ type MetaOption struct {
 // RmOption provides compatibility with rm command options
 // https://man7.org/linux/man-pages/man1/rm.1.html
 type RmOption struct {
-	Interactive bool `short:"i" description:"(dummy) prompt before every removal"`
-	Recursive   bool `short:"r" long:"recursive" description:"(dummy) remove directories and their contents recursively"`
-	Recursive2  bool `short:"R" description:"(dummy) same as -r"`
-	Force       bool `short:"f" long:"force" description:"(dummy) ignore nonexistent files, never prompt"`
-	Directory   bool `short:"d" long:"dir" description:"(dummy) remove empty directories"`
-	Verbose     bool `short:"v" long:"verbose" description:"(dummy) explain what is being done"`
+	Interactive     bool   `short:"i" description:"prompt before every removal"`
+	InteractiveOnce bool   `short:"I" description:"prompt once before removing more than three files, or when removing recursively"`
+	InteractiveWhen string `long:"interactive" description:"prompt never, once (-I), or always (-i)" optional:"yes" optional-value:"always" choice:"never" choice:"once" choice:"always"`
+	Recursive       bool   `short:"r" long:"recursive" description:"remove directories and their contents recursively"`
+	Recursive2      bool   `short:"R" description:"same as -r"`
+	Force           bool   `short:"f" long:"force" description:"ignore nonexistent files, never prompt"`
+	Directory       bool   `short:"d" long:"dir" description:"remove empty directories"`
+	Verbose         bool   `short:"v" long:"verbose" description:"explain what is being done"`
+	OneFileSystem   bool   `long:"one-file-system" description:"refuse to remove a directory containing a mount point of another file system"`
+	PreserveRoot    string `long:"preserve-root" description:"do not remove '/' (default); with 'all', reject any argument on a separate device from its parent" optional:"yes" optional-value:"root" choice:"root" choice:"all"`
+	NoPreserveRoot  bool   `long:"no-preserve-root" description:"do not treat '/' specially"`
 }
 
 type CLI struct {
 	version Version
 	option  Option
+	command string
 	config  *config.Config
 	runID   string
 	manager *trash.Manager
+	protect []protectRule
+
+	// trashConfig is used to open storages other than the ones of the manager
+	trashConfig trash.Config
+
+	directDelete []directDeleteRule
 }
 
 var runID = sync.OnceValue(func() string {
@@ -60,10 +93,12 @@ var runID = sync.OnceValue(func() string {
 
 func Run(v Version) error {
 	var opt Option
-	parser := flags.NewParser(&opt, flags.Default)
-	parser.Name = v.AppName
-	parser.Usage = "[-b | files...]"
-	args, err := parser.Parse()
+	parser := newParser(&opt, v.AppName)
+	args, err := fileArgs(parser, os.Args[1:])
+	if err != nil {
+		return err
+	}
+	args, err = parser.ParseArgs(args)
 	if err != nil {
 		if flags.WroteHelp(err) {
 			return nil
@@ -112,8 +147,22 @@ func Run(v Version) error {
 		Strategy:     trash.Strategy(cfg.Core.Trash.Strategy),
 		HomeFallback: cfg.Core.HomeFallback,
 		History:      cfg.History,
+		Retention:    cfg.Core.Retention,
 		GomiDir:      cfg.Core.Trash.GomiDir,
+		HomeTrashDir: cfg.Core.Trash.HomeDir,
+		JournalDir:   env.GOMI_JOURNAL_DIR,
 		RunID:        runID(),
+
+		PreservePaths:       cfg.Core.Trash.PreservePaths,
+		CreateExternalTrash: cfg.Core.Trash.CreateExternal,
+		UseCompression:      cfg.Core.Trash.Compression.Enable,
+		Compression:         cfg.Core.Trash.Compression,
+		Checksum:            cfg.Core.Trash.Checksum,
+		Dedup:               cfg.Core.Trash.Dedup,
+		ChecksumMismatch:    trash.MismatchPolicy(cfg.Core.Restore.OnChecksumMismatch),
+	}
+	if opt.IgnoreChecksum {
+		trashConfig.ChecksumMismatch = trash.MismatchIgnore
 	}
 
 	// Initialize storage manager with appropriate implementations
@@ -130,7 +179,7 @@ func Run(v Version) error {
 	case trash.StrategyAuto:
 		// Default to XDG with optional legacy fallback
 		managerOpts = append(managerOpts, trash.WithStorage(xdg.NewStorage))
-		if exist, err := trash.IsExistLegacy(); err != nil {
+		if exist, err := trash.IsExistLegacy(trashConfig.GomiDir); err != nil {
 			slog.Error("failed to check if legacy storage exists", "error", err)
 		} else if exist {
 			managerOpts = append(managerOpts, trash.WithStorage(legacy.NewStorage))
@@ -146,12 +195,33 @@ func Run(v Version) error {
 		return fmt.Errorf("failed to initialize storage manager: %w", err)
 	}
 
+	// Nested commands are joined by a space (e.g. "config check")
+	var command string
+	for active := parser.Active; active != nil; active = active.Active {
+		command = strings.TrimSpace(command + " " + active.Name)
+	}
+
+	protect, err := compileProtectRules(cfg.Core.Protect)
+	if err != nil {
+		return err
+	}
+
+	directDelete, err := compileDirectDeleteRules(cfg.Core.DirectDelete)
+	if err != nil {
+		return err
+	}
+
 	cli := CLI{
 		version: v,
 		option:  opt,
+		command: command,
 		config:  cfg,
 		runID:   runID(),
 		manager: manager,
+		protect: protect,
+
+		directDelete: directDelete,
+		trashConfig:  trashConfig,
 	}
 
 	if err := cli.Run(args); err != nil {
@@ -170,6 +240,42 @@ func (c CLI) Run(args []string) error {
 	case c.option.Restore:
 		return c.Restore()
 
+	case c.command == "empty":
+		return c.Empty()
+
+	case c.command == "list":
+		return c.List()
+
+	case c.command == "restore":
+		return c.RestoreByPatterns(args)
+
+	case c.command == "undo":
+		return c.Undo()
+
+	case c.command == "config check":
+		return c.ConfigCheck(args)
+
+	case c.command == "doctor":
+		return c.Doctor()
+
+	case c.command == "migrate":
+		return c.Migrate()
+
+	case c.command == "import":
+		return c.Import(args)
+
+	case c.command == "export":
+		return c.Export()
+
+	case c.command == "import-archive":
+		return c.ImportArchive(args)
+
+	case c.command == "verify":
+		return c.Verify()
+
+	case c.command == "stats":
+		return c.Stats()
+
 	default:
 		switch c.option.Meta.Debug {
 		case "live":
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/babarot/gomi/internal/config"
+)
+
+// ConfigCommand groups subcommands about the configuration
+type ConfigCommand struct {
+	Check ConfigCheckCommand `command:"check" description:"Validate the config and show which rules match the given paths"`
+}
+
+// ConfigCheckCommand validates the config and explains how paths are treated
+type ConfigCheckCommand struct{}
+
+// ConfigCheck reports that the config is valid, and for each path,
+// which rule decides whether it can be moved to trash
+func (c *CLI) ConfigCheck(args []string) error {
+	slog.Debug("cli.config-check started")
+	defer slog.Debug("cli.config-check finished")
+
+	// The config has already been loaded and validated at this point
+	path := c.option.Config
+	if path == "" {
+		var err error
+		path, err = config.DefaultConfigPath()
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("config is valid: %s\n", path)
+	for _, warning := range c.config.Warnings() {
+		fmt.Printf("warning: %s\n", warning)
+	}
+
+	for _, arg := range args {
+		expanded, err := expandPath(arg)
+		if err != nil {
+			return fmt.Errorf("failed to expand path: %w", err)
+		}
+		abs, err := filepath.Abs(expanded)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		fmt.Printf("%s: %s\n", arg, c.explainPath(abs))
+	}
+
+	return nil
+}
+
+// explainPath describes which rule applies when the path is moved to trash
+func (c *CLI) explainPath(path string) string {
+	if isForbiddenPath(path, forbiddenPaths) || isForbiddenPath(path, c.trashDirs()) {
+		return "refused (built-in forbidden path)"
+	}
+	if path == string(filepath.Separator) {
+		return "refused (root directory, overridable with --no-preserve-root)"
+	}
+	if rule := matchProtectRule(c.protect, path); rule != nil {
+		return fmt.Sprintf("%s (%s)", rule.Action, rule)
+	}
+	if rule := matchDirectDeleteRule(c.directDelete, path); rule != nil {
+		if rule.MinSize != "" {
+			return fmt.Sprintf("deleted directly if at least %s (%s)", rule.MinSize, rule)
+		}
+		return fmt.Sprintf("deleted directly (%s)", rule)
+	}
+	return "allowed (no rule matched)"
+}
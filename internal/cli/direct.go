@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/babarot/gomi/internal/config"
+	"github.com/babarot/gomi/internal/utils/env"
+	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/docker/go-units"
+	"github.com/gobwas/glob"
+)
+
+// auditMu serializes writes to the audit log
+var auditMu sync.Mutex
+
+// directDeleteRule is the compiled form of config.DirectDeleteRule
+type directDeleteRule struct {
+	config.DirectDeleteRule
+
+	// index is the position of the rule in the config
+	index int
+
+	glob glob.Glob
+
+	// baseName is true if the glob is matched against the base name only
+	baseName bool
+
+	// minSize is the size threshold in bytes, 0 means any size
+	minSize int64
+}
+
+// auditEntry is a line of the audit log
+type auditEntry struct {
+	Time  time.Time `json:"time"`
+	RunID string    `json:"run_id"`
+	Path  string    `json:"path"`
+	Size  int64     `json:"size"`
+	IsDir bool      `json:"is_dir"`
+	Rule  string    `json:"rule"`
+
+	// Result is auditDeleting when the deletion starts, then auditDeleted or auditFailed
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Results of a deletion in the audit log
+const (
+	auditDeleting = "deleting"
+	auditDeleted  = "deleted"
+	auditFailed   = "failed"
+)
+
+// compileDirectDeleteRules compiles the direct delete rules in the config
+func compileDirectDeleteRules(rules []config.DirectDeleteRule) ([]directDeleteRule, error) {
+	compiled := make([]directDeleteRule, 0, len(rules))
+	for i, rule := range rules {
+		g, err := glob.Compile(rule.Glob, filepath.Separator)
+		if err != nil {
+			return nil, fmt.Errorf("invalid direct_delete glob %q: %w", rule.Glob, err)
+		}
+		r := directDeleteRule{
+			DirectDeleteRule: rule,
+			index:            i,
+			glob:             g,
+			baseName:         !strings.ContainsRune(rule.Glob, filepath.Separator),
+		}
+		if rule.MinSize != "" {
+			size, err := units.FromHumanSize(rule.MinSize)
+			if err != nil {
+				return nil, fmt.Errorf("invalid direct_delete min_size %q: %w", rule.MinSize, err)
+			}
+			r.minSize = size
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// matchPath reports whether the path matches the glob of the rule
+func (r directDeleteRule) matchPath(path string) bool {
+	if r.baseName {
+		return r.glob.Match(filepath.Base(path))
+	}
+	return r.glob.Match(path)
+}
+
+// String describes the rule as written in the config
+func (r directDeleteRule) String() string {
+	s := fmt.Sprintf("core.direct_delete[%d] glob=%q", r.index, r.Glob)
+	if r.MinSize != "" {
+		s += fmt.Sprintf(" min_size=%s", r.MinSize)
+	}
+	return s
+}
+
+// matchDirectDeleteRule returns the first rule whose glob matches the path, or nil if none.
+// The size threshold is not checked here.
+func matchDirectDeleteRule(rules []directDeleteRule, path string) *directDeleteRule {
+	path = filepath.Clean(path)
+	for _, rule := range rules {
+		if rule.matchPath(path) {
+			return &rule
+		}
+	}
+	return nil
+}
+
+// deleteDirectly permanently deletes the path if it matches a direct delete rule.
+// It returns false if the path should be moved to trash as usual.
+func (c *CLI) deleteDirectly(fi os.FileInfo, path string) (bool, error) {
+	if c.option.TrashAnyway {
+		return false, nil
+	}
+	rule := matchDirectDeleteRule(c.directDelete, path)
+	if rule == nil {
+		return false, nil
+	}
+
+	size, err := fs.DirSize(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to get size: %w", err)
+	}
+	if size < rule.minSize {
+		slog.Debug("direct delete skipped: below min_size", "path", path, "size", size, "rule", rule.String())
+		return false, nil
+	}
+
+	// The deletion is recorded before the path is gone, so that it is never deleted unrecorded
+	entry := auditEntry{
+		Time:   time.Now(),
+		RunID:  c.runID,
+		Path:   path,
+		Size:   size,
+		IsDir:  fi.IsDir(),
+		Rule:   rule.String(),
+		Result: auditDeleting,
+	}
+	if err := writeAuditLog(entry); err != nil {
+		return false, fmt.Errorf("failed to write audit log, not deleting directly: %w", err)
+	}
+
+	removeErr := os.RemoveAll(path)
+	entry.Time = time.Now()
+	entry.Result = auditDeleted
+	if removeErr != nil {
+		entry.Result = auditFailed
+		entry.Error = removeErr.Error()
+	}
+	if err := writeAuditLog(entry); err != nil {
+		// The deletion is already recorded as started, so only report the failure
+		slog.Error("failed to write audit log", "error", err)
+		fmt.Fprintf(os.Stderr, "warning: failed to write audit log: %v\n", err)
+	}
+	if removeErr != nil {
+		return false, fmt.Errorf("failed to delete directly: %w", removeErr)
+	}
+	slog.Info("deleted directly", "path", path, "size", size, "rule", rule.String())
+	return true, nil
+}
+
+// writeAuditLog appends the entry to the audit log as a JSON line and syncs it
+func writeAuditLog(entry auditEntry) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	path := env.GOMI_AUDIT_LOG_PATH
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		return err
+	}
+	// The entry must be on disk before the path is deleted
+	return f.Sync()
+}
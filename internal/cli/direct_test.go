@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/babarot/gomi/internal/config"
+	"github.com/babarot/gomi/internal/utils/env"
+)
+
+func TestMatchDirectDeleteRule(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for direct delete rules")
+	}
+
+	rules, err := compileDirectDeleteRules([]config.DirectDeleteRule{
+		{Glob: "node_modules"},
+		{Glob: "**/target", MinSize: "1KB"},
+	})
+	if err != nil {
+		t.Fatalf("compileDirectDeleteRules() error = %v", err)
+	}
+	if rules[1].minSize != 1000 {
+		t.Errorf("minSize = %d, want 1000", rules[1].minSize)
+	}
+
+	tests := []struct {
+		path  string
+		index int // -1 means no rule matched
+	}{
+		{"/home/user/src/app/node_modules", 0},
+		{"/home/user/src/app/node_modules/react", -1},
+		{"/home/user/src/app/target", 1},
+		{"/home/user/src/app/target.txt", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := matchDirectDeleteRule(rules, tt.path)
+			switch {
+			case rule == nil && tt.index != -1:
+				t.Errorf("matchDirectDeleteRule() = nil, want rule %d", tt.index)
+			case rule != nil && rule.index != tt.index:
+				t.Errorf("matchDirectDeleteRule() = rule %d, want %d", rule.index, tt.index)
+			}
+		})
+	}
+}
+
+func TestDeleteDirectly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for direct delete")
+	}
+
+	rules, err := compileDirectDeleteRules([]config.DirectDeleteRule{{Glob: "node_modules"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &CLI{runID: "run", directDelete: rules}
+	dir := t.TempDir()
+	mkdir := func() (os.FileInfo, string) {
+		path := filepath.Join(dir, "node_modules")
+		if err := os.MkdirAll(filepath.Join(path, "react"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi, path
+	}
+
+	orig := env.GOMI_AUDIT_LOG_PATH
+	t.Cleanup(func() { env.GOMI_AUDIT_LOG_PATH = orig })
+
+	// The deletion is recorded before and after the path is deleted
+	env.GOMI_AUDIT_LOG_PATH = filepath.Join(dir, "log", "audit.log")
+	fi, path := mkdir()
+	deleted, err := c.deleteDirectly(fi, path)
+	if err != nil || !deleted {
+		t.Fatalf("deleteDirectly() = %v, %v, want true", deleted, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s is not deleted: %v", path, err)
+	}
+	data, err := os.ReadFile(env.GOMI_AUDIT_LOG_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Path != path || entry.RunID != "run" || !entry.IsDir {
+			t.Errorf("got entry %+v", entry)
+		}
+		results = append(results, entry.Result)
+	}
+	if want := []string{auditDeleting, auditDeleted}; !slices.Equal(results, want) {
+		t.Errorf("got results %q, want %q", results, want)
+	}
+
+	// A path is not deleted unrecorded
+	env.GOMI_AUDIT_LOG_PATH = filepath.Join(env.GOMI_AUDIT_LOG_PATH, "audit.log")
+	fi, path = mkdir()
+	if deleted, err := c.deleteDirectly(fi, path); err == nil || deleted {
+		t.Errorf("deleteDirectly() = %v, %v, want an error", deleted, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("%s is deleted without being recorded: %v", path, err)
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash"
+)
+
+// DoctorCommand finds and repairs inconsistencies in the trash
+type DoctorCommand struct {
+	Fix     bool   `long:"fix" description:"Repair the problems found"`
+	Orphans string `long:"orphans" description:"How --fix handles files in the trash without metadata" choice:"adopt" choice:"delete" default:"adopt"`
+}
+
+// Doctor scans every storage and reports orphans, unparsable metadata,
+// unsafe permissions, leftover temporary files and duplicates.
+// With --fix, each problem is repaired.
+func (c *CLI) Doctor() error {
+	slog.Debug("cli.doctor started")
+	defer slog.Debug("cli.doctor finished")
+
+	opt := c.option.Doctor
+	problems, err := c.manager.Check()
+	if err != nil {
+		// Problems found in the other storages are still worth reporting
+		fmt.Fprintf(os.Stderr, "gomi: %v\n", err)
+	}
+	if len(problems) == 0 {
+		fmt.Println("No problems found")
+		return err
+	}
+
+	var fixed, failed int
+	for _, p := range problems {
+		fmt.Println(p)
+		if !opt.Fix {
+			continue
+		}
+		if !p.Fixable() {
+			fmt.Println("  not fixed: cannot be fixed automatically")
+			continue
+		}
+		if err := p.Fix(trash.OrphanAction(opt.Orphans)); err != nil {
+			fmt.Fprintf(os.Stderr, "  failed to fix: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Println("  fixed")
+		fixed++
+	}
+
+	if !opt.Fix {
+		fmt.Printf("Found %d problem(s), run with --fix to repair them\n", len(problems))
+		return err
+	}
+	fmt.Printf("Fixed %d of %d problem(s)\n", fixed, len(problems))
+	if failed > 0 {
+		return fmt.Errorf("failed to fix %d problem(s)", failed)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dustin/go-humanize"
+)
+
+// EmptyCommand permanently deletes files in the trash without launching the UI
+type EmptyCommand struct {
+	Selector SelectorOption `group:"Selector Options"`
+
+	All    bool `long:"all" description:"Permanently delete every file in the trash"`
+	DryRun bool `short:"n" long:"dry-run" description:"Show what would be deleted without deleting anything"`
+}
+
+// Empty permanently deletes files in the trash matching the given selectors
+func (c *CLI) Empty() error {
+	slog.Debug("cli.empty started")
+	defer slog.Debug("cli.empty finished")
+
+	opt := c.option.Empty
+	if opt.Selector.isEmpty() && !opt.All {
+		return errors.New("no selector given, use --all to empty the whole trash")
+	}
+
+	sel, err := opt.Selector.parse()
+	if err != nil {
+		return err
+	}
+
+	// History filters are meant for display purposes,
+	// so every file in the trash is a candidate here
+	files, err := c.manager.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to list trash contents: %w", err)
+	}
+
+	targets := sel.selectFiles(files)
+	if len(targets) == 0 {
+		fmt.Println("No files in the trash matched the given selectors")
+		return nil
+	}
+
+	var (
+		removed int
+		freed   int64
+		failed  []string
+	)
+	for _, file := range targets {
+		size, err := file.StoredSize()
+		if err != nil {
+			slog.Warn("failed to get size", "path", file.TrashPath, "error", err)
+		}
+
+		if opt.DryRun {
+			fmt.Printf("would remove: %s (%s)\n", file.OriginalPath, humanize.Bytes(uint64(size)))
+			removed++
+			freed += size
+			continue
+		}
+
+		if err := c.manager.Remove(file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to remove %s: %v\n", file.OriginalPath, err)
+			failed = append(failed, file.OriginalPath)
+			continue
+		}
+		if c.option.Rm.Verbose {
+			fmt.Printf("removed: %s\n", file.OriginalPath)
+		}
+		removed++
+		freed += size
+	}
+
+	if opt.DryRun {
+		fmt.Printf("Would remove %d file(s), freeing %s\n", removed, humanize.Bytes(uint64(freed)))
+	} else {
+		fmt.Printf("Removed %d file(s), freed %s\n", removed, humanize.Bytes(uint64(freed)))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove files %v", failed)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/babarot/gomi/internal/trash/archive"
+	"github.com/dustin/go-humanize"
+)
+
+// ExportCommand packs files in the trash into a portable archive
+type ExportCommand struct {
+	Selector SelectorOption `group:"Selector Options"`
+
+	All    bool   `long:"all" description:"Export every file in the trash"`
+	Output string `short:"o" long:"output" value-name:"FILE" description:"Archive to write (.tar.zst or .tar.gz)" required:"true"`
+}
+
+// Export writes the files in the trash matching the given selectors into an archive,
+// together with their metadata. The files are left in the trash.
+func (c *CLI) Export() error {
+	slog.Debug("cli.export started")
+	defer slog.Debug("cli.export finished")
+
+	opt := c.option.Export
+	if opt.Selector.isEmpty() && !opt.All {
+		return errors.New("no selector given, use --all to export the whole trash")
+	}
+	if _, err := archive.FormatOf(opt.Output); err != nil {
+		return err
+	}
+
+	sel, err := opt.Selector.parse()
+	if err != nil {
+		return err
+	}
+
+	files, err := c.manager.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to list trash contents: %w", err)
+	}
+
+	targets := sel.selectFiles(files)
+	if len(targets) == 0 {
+		fmt.Println("No files in the trash matched the given selectors")
+		return nil
+	}
+
+	var total int64
+	for _, file := range targets {
+		size, err := file.StoredSize()
+		if err != nil {
+			slog.Warn("failed to get size", "path", file.TrashPath, "error", err)
+		}
+		total += size
+		if c.option.Rm.Verbose {
+			fmt.Printf("exporting: %s\n", file.OriginalPath)
+		}
+	}
+
+	if err := archive.Write(opt.Output, targets); err != nil {
+		return fmt.Errorf("failed to export: %w", err)
+	}
+	fmt.Printf("Exported %d file(s) (%s) to %s\n", len(targets), humanize.Bytes(uint64(total)), opt.Output)
+	return nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/xdg"
+)
+
+// ImportCommand moves files of trash directories not managed by gomi into the trash
+type ImportCommand struct{}
+
+// Import merges every file of the given trash directories into the XDG home trash,
+// keeping their original paths and deletion dates
+func (c *CLI) Import(args []string) error {
+	slog.Debug("cli.import started")
+	defer slog.Debug("cli.import finished")
+
+	if len(args) == 0 {
+		return errors.New("no trash directory given")
+	}
+
+	storage, err := xdg.NewStorage(c.trashConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open xdg trash: %w", err)
+	}
+	dst := storage.(*xdg.Storage)
+
+	var imported, failed int
+	for _, arg := range args {
+		err := dst.ImportRoot(arg, func(file *trash.File, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to import %s: %v\n", file.OriginalPath, err)
+				failed++
+				return
+			}
+			if c.option.Rm.Verbose {
+				fmt.Printf("imported: %s\n", file.OriginalPath)
+			}
+			imported++
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", arg, err)
+		}
+	}
+
+	fmt.Printf("Imported %d file(s)\n", imported)
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d file(s), run \"gomi import -- %s\" again to retry", failed, strings.Join(args, " "))
+	}
+	return nil
+}
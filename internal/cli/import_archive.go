@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/archive"
+	"github.com/babarot/gomi/internal/trash/xdg"
+	"github.com/babarot/gomi/internal/utils/fs"
+)
+
+// ImportArchiveCommand puts files of an archive written by "gomi export" back
+type ImportArchiveCommand struct {
+	Restore bool `long:"restore" description:"Restore the files to their original paths instead of the trash"`
+}
+
+// ImportArchive extracts an archive written by "gomi export" and moves its files
+// into the XDG home trash, keeping their original paths, deletion dates and run IDs.
+// With --restore, they are moved straight to their original paths.
+func (c *CLI) ImportArchive(args []string) error {
+	slog.Debug("cli.import-archive started")
+	defer slog.Debug("cli.import-archive finished")
+
+	if len(args) != 1 {
+		return errors.New("give exactly one archive to import")
+	}
+	src := args[0]
+	opt := c.option.ImportArchive
+
+	// Files imported into the trash are extracted in it, so that they are renamed into it
+	var dst *xdg.Storage
+	stage := func() (string, error) { return os.MkdirTemp("", "gomi-import-") }
+	if !opt.Restore {
+		storage, err := xdg.NewStorage(c.trashConfig)
+		if err != nil {
+			return fmt.Errorf("failed to open xdg trash: %w", err)
+		}
+		dst = storage.(*xdg.Storage)
+		stage = dst.Stage
+	}
+
+	dir, err := stage()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	manifest, err := archive.Extract(src, dir)
+	if err != nil {
+		return err
+	}
+
+	var imported, failed int
+	for _, entry := range manifest.Entries {
+		file := entry.File()
+		if err := c.trashConfig.VerifyBeforeRestore(file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to import %s: %v\n", file.OriginalPath, err)
+			failed++
+			continue
+		}
+		if opt.Restore {
+			err = restoreFromArchive(file)
+		} else {
+			err = dst.Import(file.TrashPath, file)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to import %s: %v\n", file.OriginalPath, err)
+			failed++
+			continue
+		}
+		if c.option.Rm.Verbose {
+			fmt.Printf("imported: %s\n", file.OriginalPath)
+		}
+		imported++
+	}
+
+	if opt.Restore {
+		fmt.Printf("Restored %d file(s) from %s\n", imported, src)
+	} else {
+		fmt.Printf("Imported %d file(s) from %s\n", imported, src)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d file(s)", failed)
+	}
+	return nil
+}
+
+// restoreFromArchive moves an extracted file to its original path,
+// decompressing it if it was compressed in the trash
+func restoreFromArchive(file *trash.File) error {
+	if _, err := os.Lstat(file.OriginalPath); err == nil {
+		return fmt.Errorf("%w: %s", trash.ErrFileExists, file.OriginalPath)
+	}
+	if file.IsCompressed() {
+		if err := os.MkdirAll(filepath.Dir(file.OriginalPath), 0755); err != nil {
+			return err
+		}
+		if err := trash.Decompress(file, file.OriginalPath); err != nil {
+			return err
+		}
+	} else if err := fs.Move(file.TrashPath, file.OriginalPath, true); err != nil {
+		return err
+	}
+	trash.RestoreMetadata(file, file.OriginalPath)
+	return nil
+}
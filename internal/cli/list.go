@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/dustin/go-humanize"
+)
+
+// ListCommand prints files in the trash without launching the UI
+type ListCommand struct {
+	JSON   bool `long:"json" description:"Print as a JSON array"`
+	NDJSON bool `long:"ndjson" description:"Print as newline-delimited JSON"`
+	All    bool `short:"a" long:"all" description:"Ignore history filters and show every file in the trash"`
+}
+
+// listEntry is the representation of a trashed file in list output
+type listEntry struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"original_path"`
+	TrashPath    string    `json:"trash_path"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	Size         int64     `json:"size"`
+	StoredSize   int64     `json:"stored_size"`
+	Compression  string    `json:"compression,omitempty"`
+	IsDir        bool      `json:"is_dir"`
+	Storage      string    `json:"storage"`
+	RunID        string    `json:"run_id,omitempty"`
+}
+
+// List prints files in the trash as a table or JSON
+func (c *CLI) List() error {
+	slog.Debug("cli.list started")
+	defer slog.Debug("cli.list finished")
+
+	opt := c.option.List
+	if opt.JSON && opt.NDJSON {
+		return errors.New("--json and --ndjson cannot be used together")
+	}
+
+	var (
+		files []*trash.File
+		err   error
+	)
+	if opt.All {
+		files, err = c.manager.ListAll()
+	} else {
+		files, err = c.manager.List()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list trash contents: %w", err)
+	}
+
+	files = c.filterFiles(files)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].DeletedAt.After(files[j].DeletedAt)
+	})
+
+	entries := make([]listEntry, len(files))
+	for i, file := range files {
+		entries[i] = newListEntry(file)
+	}
+
+	switch {
+	case opt.JSON:
+		return printJSON(os.Stdout, entries)
+	case opt.NDJSON:
+		return printNDJSON(os.Stdout, entries)
+	default:
+		return printTable(os.Stdout, entries)
+	}
+}
+
+func newListEntry(file *trash.File) listEntry {
+	// The size of a compressed file is the one recorded before compression
+	size := file.Size
+	if file.IsDir && !file.IsCompressed() {
+		if dirSize, err := file.StoredSize(); err == nil {
+			size = dirSize
+		}
+	}
+	storedSize := size
+	if file.IsCompressed() {
+		if s, err := file.StoredSize(); err == nil {
+			storedSize = s
+		}
+	}
+
+	var storage string
+	if s := file.GetStorage(); s != nil {
+		storage = s.Info().Type.String()
+	}
+
+	return listEntry{
+		ID:           file.ID(),
+		Name:         file.Name,
+		OriginalPath: file.OriginalPath,
+		TrashPath:    file.TrashPath,
+		DeletedAt:    file.DeletedAt,
+		Size:         size,
+		StoredSize:   storedSize,
+		Compression:  file.Compression,
+		IsDir:        file.IsDir,
+		Storage:      storage,
+		RunID:        file.RunID,
+	}
+}
+
+func printJSON(w io.Writer, entries []listEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+func printNDJSON(w io.Writer, entries []listEntry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printTable(w io.Writer, entries []listEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDELETED AT\tSIZE\tSTORED\tTYPE\tSTORAGE\tNAME\tORIGINAL PATH\tTRASH PATH")
+	for _, entry := range entries {
+		kind := "file"
+		if entry.IsDir {
+			kind = "dir"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID,
+			entry.DeletedAt.Format(time.DateTime),
+			humanize.Bytes(uint64(entry.Size)),
+			humanize.Bytes(uint64(entry.StoredSize)),
+			kind,
+			entry.Storage,
+			entry.Name,
+			entry.OriginalPath,
+			entry.TrashPath,
+		)
+	}
+	return tw.Flush()
+}
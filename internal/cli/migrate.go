@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/legacy"
+	"github.com/babarot/gomi/internal/trash/xdg"
+)
+
+// MigrateCommand moves files of the legacy trash to another storage
+type MigrateCommand struct {
+	To     string `long:"to" description:"Storage to move the files to" choice:"xdg" required:"true"`
+	DryRun bool   `short:"n" long:"dry-run" description:"Show what would be migrated without moving anything"`
+}
+
+// Migrate moves every file of the legacy trash into the XDG home trash,
+// keeping their original paths, deletion dates and run IDs.
+// Each file is removed from the legacy history as soon as it has been moved,
+// so an interrupted migration is resumed by running it again.
+// Once the history is empty, it is retired and the legacy storage is no longer used.
+func (c *CLI) Migrate() error {
+	slog.Debug("cli.migrate started")
+	defer slog.Debug("cli.migrate finished")
+
+	opt := c.option.Migrate
+	exist, err := trash.IsExistLegacy(c.trashConfig.GomiDir)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		fmt.Println("No legacy trash to migrate")
+		return nil
+	}
+
+	storage, err := legacy.NewStorage(c.trashConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open legacy trash: %w", err)
+	}
+	src := storage.(*legacy.Storage)
+	storage, err = xdg.NewStorage(c.trashConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open xdg trash: %w", err)
+	}
+	dst := storage.(trash.Importer)
+
+	files, err := src.List()
+	if err != nil {
+		return fmt.Errorf("failed to list legacy trash: %w", err)
+	}
+
+	var (
+		migrated int
+		failed   []string
+	)
+	for _, file := range files {
+		if opt.DryRun {
+			fmt.Printf("would migrate: %s\n", file.OriginalPath)
+			migrated++
+			continue
+		}
+
+		if _, err := os.Lstat(file.TrashPath); os.IsNotExist(err) {
+			// Nothing to move, which "gomi doctor" reports as well
+			fmt.Fprintf(os.Stderr, "skipped %s: missing from the legacy trash\n", file.OriginalPath)
+			failed = append(failed, file.OriginalPath)
+			continue
+		}
+		if err := src.Detach(file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to migrate %s: %v\n", file.OriginalPath, err)
+			failed = append(failed, file.OriginalPath)
+			continue
+		}
+		if err := dst.Import(file.TrashPath, file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to migrate %s: %v\n", file.OriginalPath, err)
+			failed = append(failed, file.OriginalPath)
+			continue
+		}
+		if err := src.Forget(file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to migrate %s: %v\n", file.OriginalPath, err)
+			failed = append(failed, file.OriginalPath)
+			continue
+		}
+		if c.option.Rm.Verbose {
+			fmt.Printf("migrated: %s\n", file.OriginalPath)
+		}
+		migrated++
+	}
+
+	if opt.DryRun {
+		fmt.Printf("Would migrate %d file(s) to %s\n", migrated, opt.To)
+		return nil
+	}
+	fmt.Printf("Migrated %d file(s) to %s\n", migrated, opt.To)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to migrate %d file(s), run \"gomi migrate --to %s\" again or \"gomi doctor\" to inspect them", len(failed), opt.To)
+	}
+	if err := src.Retire(); err != nil {
+		return errors.Join(errors.New("every file has been migrated"), err)
+	}
+	fmt.Println("Retired legacy history, the legacy trash is no longer used")
+	return nil
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/babarot/gomi/internal/config"
+	"github.com/babarot/gomi/internal/ui"
+	"github.com/gobwas/glob"
+)
+
+const (
+	protectActionRefuse  = "refuse"
+	protectActionConfirm = "confirm"
+	protectActionWarn    = "warn"
+)
+
+// systemProtectRules is the protect default refusing critical system directories
+// and their contents. It applies after the rules of the config, so that a rule of
+// core.protect matching one of them (e.g. with action "warn") takes precedence.
+var systemProtectRules = []config.ProtectRule{
+	{Glob: "/etc/**"},
+	{Glob: "/usr/**"},
+	{Glob: "/var/**"},
+	{Glob: "/bin/**"},
+	{Glob: "/sbin/**"},
+	{Glob: "/lib/**"},
+	{Glob: "/lib64/**"},
+}
+
+// promptMu serializes prompts shown while files are processed in parallel
+var promptMu sync.Mutex
+
+// protectRule is the compiled form of config.ProtectRule
+type protectRule struct {
+	config.ProtectRule
+
+	// index is the position of the rule in the config
+	index int
+
+	// system tells that the rule is one of systemProtectRules
+	system bool
+
+	glob    glob.Glob
+	pattern *regexp.Regexp
+
+	// prefix is the literal leading directory of the glob, if any.
+	// Moving one of its ancestors to trash would remove protected paths too.
+	prefix string
+}
+
+// compileProtectRules compiles the protect rules in the config, followed by systemProtectRules
+func compileProtectRules(rules []config.ProtectRule) ([]protectRule, error) {
+	compiled := make([]protectRule, 0, len(rules)+len(systemProtectRules))
+	for i, rule := range slices.Concat(rules, systemProtectRules) {
+		r := protectRule{ProtectRule: rule, index: i, system: i >= len(rules)}
+		if r.Action == "" {
+			r.Action = protectActionRefuse
+		}
+		if rule.Glob != "" {
+			g, err := glob.Compile(rule.Glob, filepath.Separator)
+			if err != nil {
+				return nil, fmt.Errorf("invalid protect glob %q: %w", rule.Glob, err)
+			}
+			r.glob = g
+			r.prefix = globPrefix(rule.Glob)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid protect pattern %q: %w", rule.Pattern, err)
+			}
+			r.pattern = re
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// globPrefix returns the leading directory of the glob that has no special characters
+func globPrefix(pattern string) string {
+	i := strings.IndexAny(pattern, `*?[{\`)
+	if i == -1 {
+		return pattern
+	}
+	return pattern[:strings.LastIndex(pattern[:i], string(filepath.Separator))+1]
+}
+
+// match reports whether moving the path to trash is guarded by the rule
+func (r protectRule) match(path string) bool {
+	if r.glob != nil {
+		if r.glob.Match(path) || r.glob.Match(path+string(filepath.Separator)) {
+			return true
+		}
+		if r.prefix != "" && strings.HasPrefix(r.prefix, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+	if r.pattern != nil && r.pattern.MatchString(path) {
+		return true
+	}
+	return false
+}
+
+// String describes the rule as written in the config
+func (r protectRule) String() string {
+	var parts []string
+	if r.Glob != "" {
+		parts = append(parts, fmt.Sprintf("glob=%q", r.Glob))
+	}
+	if r.Pattern != "" {
+		parts = append(parts, fmt.Sprintf("pattern=%q", r.Pattern))
+	}
+	parts = append(parts, "action="+r.Action)
+	if r.system {
+		return fmt.Sprintf("system directory %s", strings.Join(parts, " "))
+	}
+	return fmt.Sprintf("core.protect[%d] %s", r.index, strings.Join(parts, " "))
+}
+
+// matchProtectRule returns the first rule guarding the path, or nil if none
+func matchProtectRule(rules []protectRule, path string) *protectRule {
+	path = filepath.Clean(path)
+	for _, rule := range rules {
+		if rule.match(path) {
+			return &rule
+		}
+	}
+	return nil
+}
+
+// checkProtected applies the protect rules to the path.
+// It returns false if moving the path to trash must be skipped.
+func (c *CLI) checkProtected(arg, path string) (bool, error) {
+	rule := matchProtectRule(c.protect, path)
+	if rule == nil {
+		return true, nil
+	}
+
+	switch rule.Action {
+	case protectActionWarn:
+		fmt.Fprintf(os.Stderr, "warning: %q is protected by %s\n", arg, rule)
+		return true, nil
+
+	case protectActionConfirm:
+		promptMu.Lock()
+		defer promptMu.Unlock()
+		name := filepath.Base(path)
+		err := ui.InputConfirmation(fmt.Sprintf("%q is protected. Type its name to move it to trash:", arg), name)
+		if errors.Is(err, ui.ErrInputCanceled) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to confirm: %w", err)
+		}
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("refusing to remove protected path: %q (%s)", arg, rule)
+	}
+}
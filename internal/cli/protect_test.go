@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/babarot/gomi/internal/config"
+)
+
+func TestMatchProtectRule(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for protect rules")
+	}
+
+	rules, err := compileProtectRules([]config.ProtectRule{
+		{Glob: "/home/user/.ssh/**"},
+		{Glob: "**/.git", Action: "confirm"},
+		{Pattern: "prod", Action: "warn"},
+	})
+	if err != nil {
+		t.Fatalf("compileProtectRules() error = %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		index int // -1 means no rule matched
+	}{
+		{"/home/user/.ssh/id_rsa", 0},
+		{"/home/user/.ssh", 0},
+		{"/home/user", 0}, // ancestor of a protected path
+		{"/home/user/src/repo/.git", 1},
+		{"/home/user/src/repo/main.go", -1},
+		{"/srv/prod/db", 2},
+		{"/home/other", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := matchProtectRule(rules, tt.path)
+			switch {
+			case rule == nil && tt.index != -1:
+				t.Errorf("matchProtectRule() = nil, want rule %d", tt.index)
+			case rule != nil && rule.index != tt.index:
+				t.Errorf("matchProtectRule() = rule %d, want %d", rule.index, tt.index)
+			}
+		})
+	}
+}
+
+func TestSystemProtectRules(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for protect rules")
+	}
+
+	rules, err := compileProtectRules([]config.ProtectRule{
+		{Glob: "/usr/local/**", Action: "warn"},
+	})
+	if err != nil {
+		t.Fatalf("compileProtectRules() error = %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		system bool
+		action string // empty means no rule matched
+	}{
+		{"/etc", true, "refuse"},
+		{"/etc/passwd", true, "refuse"},
+		{"/lib64", true, "refuse"},
+		// A rule of the config takes precedence
+		{"/usr/local/bin/tool", false, "warn"},
+		{"/usr/bin/env", true, "refuse"},
+		{"/etcetera", false, ""},
+		{"/home/user/etc", false, ""},
+		// "/" is refused by --preserve-root only
+		{"/", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := matchProtectRule(rules, tt.path)
+			if rule == nil {
+				if tt.action != "" {
+					t.Errorf("matchProtectRule() = nil, want %s", tt.action)
+				}
+				return
+			}
+			if rule.Action != tt.action || rule.system != tt.system {
+				t.Errorf("matchProtectRule() = %s, want action %s (system %v)", rule, tt.action, tt.system)
+			}
+		})
+	}
+}
+
+func TestGlobPrefix(t *testing.T) {
+	tests := []struct {
+		glob   string
+		prefix string
+	}{
+		{"/home/user/.ssh/**", "/home/user/.ssh/"},
+		{"/home/user/*.key", "/home/user/"},
+		{"**/.git", ""},
+		{"/etc/passwd", "/etc/passwd"},
+	}
+
+	for _, tt := range tests {
+		if got := globPrefix(tt.glob); got != tt.prefix {
+			t.Errorf("globPrefix(%q) = %q, want %q", tt.glob, got, tt.prefix)
+		}
+	}
+}
@@ -23,16 +23,6 @@ var forbiddenPaths = []string{
 
 	// gomi dir
 	"$HOME/.gomi",
-
-	// Critical system directories
-	"/",
-	"/etc",
-	"/usr",
-	"/var",
-	"/bin",
-	"/sbin",
-	"/lib",
-	"/lib64",
 }
 
 // Put moves files to trash
@@ -44,16 +34,38 @@ func (c *CLI) Put(args []string) error {
 		return errors.New("too few arguments")
 	}
 
+	for _, warning := range c.config.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	if !c.confirmOnce(args) {
+		return nil
+	}
+
 	// Use a thread-safe slice to track failed files
 	var (
-		eg     errgroup.Group
-		failed = &syncStringSlice{}
+		eg      errgroup.Group
+		failed  = &syncStringSlice{}
+		skipped = &syncStringSlice{}
 	)
 
+	// Ctrl-C lets the files being moved complete instead of leaving them half copied
+	interruption := watchInterruption()
+	defer interruption.stop()
+
+	// Prompting for each file must happen one at a time
+	if !c.config.Core.Rm.Permissive && c.option.Rm.interactiveMode() == interactiveAlways {
+		eg.SetLimit(1)
+	}
+
 	for _, arg := range args {
 		arg := arg // Create new instance of arg for goroutine
 		eg.Go(func() error {
-			return c.processFile(arg, failed)
+			if interruption.Interrupted() {
+				skipped.Append(arg)
+				return nil
+			}
+			return c.processFile(arg, failed, interruption, skipped)
 		})
 	}
 
@@ -62,6 +74,10 @@ func (c *CLI) Put(args []string) error {
 		return err
 	}
 
+	if interruption.Interrupted() {
+		return fmt.Errorf("interrupted, %d file(s) were left in place", len(skipped.Get()))
+	}
+
 	if failedFiles := failed.Get(); len(failedFiles) > 0 {
 		return fmt.Errorf("failed to process files %v", failedFiles)
 	}
@@ -69,8 +85,9 @@ func (c *CLI) Put(args []string) error {
 	return nil
 }
 
-// processFile handles the logic for moving a single file to trash
-func (c *CLI) processFile(arg string, failed *syncStringSlice) error {
+// processFile handles the logic for moving a single file to trash.
+// The file is skipped if gomi is interrupted before it starts moving.
+func (c *CLI) processFile(arg string, failed *syncStringSlice, interruption *interruption, skipped *syncStringSlice) error {
 	// Expand path (replace environment variables)
 	expandedPath, err := expandPath(arg)
 	if err != nil {
@@ -79,20 +96,30 @@ func (c *CLI) processFile(arg string, failed *syncStringSlice) error {
 	}
 
 	// Check for forbidden paths
-	if isForbiddenPath(expandedPath) {
+	if isForbiddenPath(expandedPath, forbiddenPaths) || isForbiddenPath(expandedPath, c.trashDirs()) {
 		failed.Append(arg)
 		return fmt.Errorf("refusing to remove forbidden path: %q", arg)
 	}
 
-	// Check path safety
-	unsafe, err := isUnsafePath(expandedPath)
-	if err != nil {
+	// "." and ".." are always refused like rm does
+	if isDotPath(expandedPath) {
 		failed.Append(arg)
-		return fmt.Errorf("failed to check path safety: %w", err)
+		return fmt.Errorf("refusing to remove '.' or '..' directory: %q", arg)
 	}
-	if unsafe {
-		failed.Append(arg)
-		return fmt.Errorf("refusing to remove unsafe path: %q", arg)
+
+	// Like rm, only "/" is refused by --preserve-root, and critical system
+	// directories are guarded by systemProtectRules instead
+	if c.option.Rm.preserveRoot() {
+		// Check path safety
+		unsafe, err := isUnsafePath(expandedPath)
+		if err != nil {
+			failed.Append(arg)
+			return fmt.Errorf("failed to check path safety: %w", err)
+		}
+		if unsafe {
+			failed.Append(arg)
+			return fmt.Errorf("refusing to remove unsafe path: %q (use --no-preserve-root to override)", arg)
+		}
 	}
 
 	// Get absolute path
@@ -103,7 +130,8 @@ func (c *CLI) processFile(arg string, failed *syncStringSlice) error {
 	}
 
 	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
 		if !c.option.Rm.Force {
 			failed.Append(arg)
 			return fmt.Errorf("%s: no such file or directory", arg)
@@ -113,8 +141,52 @@ func (c *CLI) processFile(arg string, failed *syncStringSlice) error {
 		}
 		return nil
 	}
+	if err != nil {
+		failed.Append(arg)
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Follow rm semantics for directories, file systems and prompting
+	if err := c.checkRemovable(arg, fi, path); err != nil {
+		failed.Append(arg)
+		return err
+	}
+	if err := c.checkFileSystem(arg, fi, path); err != nil {
+		failed.Append(arg)
+		return err
+	}
+
+	// Apply user-defined protect rules
+	ok, err := c.checkProtected(arg, path)
+	if err != nil {
+		failed.Append(arg)
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if !c.confirmRemoval(arg, fi) {
+		return nil
+	}
+
+	// Disposable paths are deleted without going to trash
+	deleted, err := c.deleteDirectly(fi, path)
+	if err != nil {
+		failed.Append(arg)
+		return err
+	}
+	if deleted {
+		if c.option.Rm.Verbose {
+			fmt.Printf("deleted directly: %s\n", path)
+		}
+		return nil
+	}
 
 	// Move to trash
+	if interruption.Interrupted() {
+		skipped.Append(arg)
+		return nil
+	}
 	err = c.manager.Put(path)
 	if err != nil {
 		if !c.option.Rm.Force {
@@ -159,8 +231,8 @@ func expandPath(path string) (string, error) {
 	return os.ExpandEnv(path), nil
 }
 
-// isForbiddenPath checks if the given path is in the forbidden paths list
-func isForbiddenPath(path string) bool {
+// isForbiddenPath checks if the given path is in the given forbidden paths list
+func isForbiddenPath(path string, forbiddenPaths []string) bool {
 	path = filepath.Clean(path)
 
 	for _, forbiddenPath := range forbiddenPaths {
@@ -176,6 +248,16 @@ func isForbiddenPath(path string) bool {
 	return false
 }
 
+// trashDirs returns the directories of the storages in use,
+// which can be configured anywhere with core.trash.home_dir or core.trash.gomi_dir
+func (c *CLI) trashDirs() []string {
+	var dirs []string
+	for _, info := range c.manager.ListStorages() {
+		dirs = append(dirs, info.Trashes...)
+	}
+	return dirs
+}
+
 // syncStringSlice is a thread-safe slice for storing strings
 type syncStringSlice struct {
 	mu    sync.Mutex
@@ -196,6 +278,12 @@ func (s *syncStringSlice) Get() []string {
 	return append([]string(nil), s.items...)
 }
 
+// isDotPath checks if the given path ends with "." or ".."
+func isDotPath(path string) bool {
+	base := filepath.Base(path)
+	return base == "." || base == ".."
+}
+
 // isUnsafePath checks if the given path is unsafe to remove
 func isUnsafePath(path string) (bool, error) {
 	// First check the original path before any normalization
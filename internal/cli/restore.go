@@ -6,11 +6,21 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/babarot/gomi/internal/trash"
 	"github.com/babarot/gomi/internal/ui"
+	"github.com/gobwas/glob"
+	"github.com/mattn/go-isatty"
 )
 
+// RestoreCommand restores files from trash without launching the UI
+type RestoreCommand struct {
+	AllMatches bool   `short:"a" long:"all-matches" description:"Restore every file matching a pattern instead of only the newest one"`
+	To         string `long:"to" description:"Restore into this directory instead of the original location" value-name:"DIR"`
+}
+
 // Restore handles the restoration of files from trash
 func (c *CLI) Restore() error {
 	slog.Debug("cli.restore started")
@@ -35,7 +45,7 @@ func (c *CLI) Restore() error {
 	}
 
 	// Show UI for file selection
-	selected, err := ui.RenderList(c.manager, filtered, c.config)
+	selected, dir, err := ui.RenderList(c.manager, filtered, c.config)
 	if err != nil {
 		return fmt.Errorf("failed to show file selection UI: %w", err)
 	}
@@ -46,7 +56,7 @@ func (c *CLI) Restore() error {
 	}
 
 	for _, file := range selected {
-		if err := c.restoreFile(file); err != nil {
+		if err := c.restoreFile(file, restoreDst(file, dir)); err != nil {
 			return fmt.Errorf("failed to restore file '%s': %w", file.Name, err)
 		}
 	}
@@ -54,6 +64,160 @@ func (c *CLI) Restore() error {
 	return nil
 }
 
+// RestoreByPatterns restores files matching the given patterns without the UI.
+// A pattern is matched against the original path (or the name if it contains
+// no path separator) exactly or as a glob, or against the ID printed by list.
+func (c *CLI) RestoreByPatterns(patterns []string) error {
+	slog.Debug("cli.restore-by-patterns started")
+	defer slog.Debug("cli.restore-by-patterns finished")
+
+	if len(patterns) == 0 {
+		return errors.New("too few arguments")
+	}
+
+	var dir string
+	if to := c.option.RestoreCmd.To; to != "" {
+		var err error
+		if dir, err = ui.ExpandDir(to); err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+
+	// Patterns name files explicitly, so history filters are not applied
+	files, err := c.manager.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to list trash contents: %w", err)
+	}
+	files = c.filterFiles(files)
+
+	// Newest first so that the first match is the most recently deleted one
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].DeletedAt.After(files[j].DeletedAt)
+	})
+
+	var (
+		targets  []*trash.File
+		seen     = make(map[string]bool)
+		notFound []string
+	)
+	for _, pattern := range patterns {
+		matched, err := matchFiles(files, pattern)
+		if err != nil {
+			return err
+		}
+		if len(matched) == 0 {
+			notFound = append(notFound, pattern)
+			continue
+		}
+		if !c.option.RestoreCmd.AllMatches {
+			matched = matched[:1]
+		}
+		for _, file := range matched {
+			if seen[file.TrashPath] {
+				continue
+			}
+			seen[file.TrashPath] = true
+			targets = append(targets, file)
+		}
+	}
+
+	var failed []string
+	for _, file := range targets {
+		if err := c.restoreFileByPattern(file, restoreDst(file, dir)); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to restore %s: %v\n", file.OriginalPath, err)
+			failed = append(failed, file.OriginalPath)
+		}
+	}
+
+	if len(notFound) > 0 {
+		return fmt.Errorf("no file in the trash matches %v", notFound)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore files %v", failed)
+	}
+	return nil
+}
+
+// matchFiles returns the files matching the pattern, keeping the order of files
+func matchFiles(files []*trash.File, pattern string) ([]*trash.File, error) {
+	// IDs are unique, so an exact ID match wins over any path match
+	for _, file := range files {
+		if file.ID() == pattern {
+			return []*trash.File{file}, nil
+		}
+	}
+
+	// Patterns without a separator are matched against the base name,
+	// others against the absolute original path
+	byName := !strings.ContainsRune(pattern, filepath.Separator)
+	if !byName {
+		abs, err := filepath.Abs(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		pattern = abs
+	}
+
+	g, err := glob.Compile(pattern, filepath.Separator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	var matched []*trash.File
+	for _, file := range files {
+		target := file.OriginalPath
+		if byName {
+			target = file.Name
+		}
+		if target == pattern || g.Match(target) {
+			matched = append(matched, file)
+		}
+	}
+	return matched, nil
+}
+
+// restoreDst returns where to restore the file: into dir if given,
+// or to its original location otherwise
+func restoreDst(file *trash.File, dir string) string {
+	if dir == "" {
+		return file.OriginalPath
+	}
+	return filepath.Join(dir, filepath.Base(file.OriginalPath))
+}
+
+// restoreFileByPattern restores a file selected by a pattern to dst. It prompts the same
+// way as the UI when a terminal is attached, and fails on conflicts otherwise
+// unless a policy other than "prompt" is configured.
+func (c *CLI) restoreFileByPattern(file *trash.File, dst string) error {
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		return c.restoreFile(file, dst)
+	}
+
+	policy, err := c.conflictPolicy()
+	if err != nil {
+		return err
+	}
+	restored, err := c.manager.RestoreOnConflict(file, dst, policy)
+	if errors.Is(err, trash.ErrSkipped) {
+		c.printVerbose("Skipped '%s': %s already exists\n", file.Name, dst)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.printVerbose("Restored '%s' to %s\n", file.Name, restored)
+	return nil
+}
+
+// conflictPolicy returns how to restore onto existing paths,
+// given by --on-conflict or core.restore.on_conflict
+func (c *CLI) conflictPolicy() (trash.ConflictPolicy, error) {
+	if c.option.OnConflict != "" {
+		return trash.ParseConflictPolicy(c.option.OnConflict)
+	}
+	return trash.ParseConflictPolicy(c.config.Core.Restore.OnConflict)
+}
+
 // filterFiles applies configured filters to the list of files
 func (c *CLI) filterFiles(files []*trash.File) []*trash.File {
 	var filtered []*trash.File
@@ -74,14 +238,17 @@ func (c *CLI) filterFiles(files []*trash.File) []*trash.File {
 	return filtered
 }
 
-// restoreFile handles the restoration of a single file
-func (c *CLI) restoreFile(file *trash.File) error {
-	originalPath := file.OriginalPath
+// restoreFile handles the restoration of a single file to dst
+func (c *CLI) restoreFile(file *trash.File, dst string) error {
+	policy, err := c.conflictPolicy()
+	if err != nil {
+		return err
+	}
 
-	// Check if the file exists at the original location
-	if _, err := os.Stat(originalPath); err == nil {
-		// File exists at original location, ask for new name if necessary
-		newName, err := ui.InputFilename(file)
+	// Check if the file exists at the destination
+	if _, err := os.Lstat(dst); err == nil && policy == trash.ConflictPrompt {
+		// File exists at the destination, ask for new name
+		newName, err := ui.InputFilename(file, dst)
 		if err != nil {
 			if errors.Is(err, ui.ErrInputCanceled) {
 				c.printVerbose("Canceled! No new filename input.\n")
@@ -89,31 +256,27 @@ func (c *CLI) restoreFile(file *trash.File) error {
 			}
 			return fmt.Errorf("failed to get new filename: %w", err)
 		}
-		originalPath = filepath.Join(filepath.Dir(originalPath), newName)
-		file.OriginalPath = originalPath
+		dst = filepath.Join(filepath.Dir(dst), newName)
 	}
 
 	// If configured, ask for confirmation
-	if c.config.Core.Restore.Confirm && !ui.Confirm(fmt.Sprintf("OK to restore? %s", filepath.Base(originalPath))) {
+	if c.config.Core.Restore.Confirm && !ui.Confirm(fmt.Sprintf("OK to restore? %s", filepath.Base(dst))) {
 		c.printVerbose("Replied no, canceled!\n")
 		return nil
 	}
 
-	// Check again if destination exists (might have been created while confirming)
-	if _, err := os.Stat(originalPath); err == nil {
-		msg := fmt.Sprintf("Caution! The same name already exists. Even so okay to restore? %s", filepath.Base(originalPath))
-		if !ui.Confirm(msg) {
-			c.printVerbose("Replied no, canceled!\n")
-			return nil
-		}
+	// Perform the restore. The destination might have been created while prompting,
+	// in which case a prompt fails rather than overwriting it.
+	restored, err := c.manager.RestoreOnConflict(file, dst, policy)
+	if errors.Is(err, trash.ErrSkipped) {
+		c.printVerbose("Skipped '%s': %s already exists\n", file.Name, dst)
+		return nil
 	}
-
-	// Perform the restore
-	if err := c.manager.Restore(file, originalPath); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to restore '%s': %w", file.Name, err)
 	}
 
-	c.printVerbose("Restored '%s' to %s\n", file.Name, originalPath)
+	c.printVerbose("Restored '%s' to %s\n", file.Name, restored)
 	return nil
 }
 
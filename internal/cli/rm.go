@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/babarot/gomi/internal/ui"
+	gomifs "github.com/babarot/gomi/internal/utils/fs"
+)
+
+// interactive represents when to prompt before moving files to trash
+type interactive int
+
+const (
+	interactiveNever interactive = iota
+	interactiveOnce
+	interactiveAlways
+)
+
+// interactiveMode returns when to prompt according to -f, -i, -I and --interactive.
+// GNU rm lets the last of these options win, but the order is not available
+// after parsing, so -f always takes precedence, followed by -i and then -I.
+func (o RmOption) interactiveMode() interactive {
+	switch {
+	case o.Force:
+		return interactiveNever
+	case o.Interactive, o.InteractiveWhen == "always":
+		return interactiveAlways
+	case o.InteractiveOnce, o.InteractiveWhen == "once":
+		return interactiveOnce
+	default:
+		return interactiveNever
+	}
+}
+
+// isRecursive returns true if -r or -R is given
+func (o RmOption) isRecursive() bool {
+	return o.Recursive || o.Recursive2
+}
+
+// preserveRoot returns false only if --no-preserve-root is given.
+// An explicit --preserve-root takes precedence over --no-preserve-root.
+func (o RmOption) preserveRoot() bool {
+	return o.PreserveRoot != "" || !o.NoPreserveRoot
+}
+
+// confirmOnce asks once before moving many files or moving recursively, like rm -I.
+// It returns false if the user declines.
+func (c *CLI) confirmOnce(args []string) bool {
+	prompt := c.oncePrompt(args)
+	return prompt == "" || ui.Confirm(prompt)
+}
+
+// oncePrompt returns the question confirmOnce asks about args,
+// or an empty string if it does not need to ask
+func (c *CLI) oncePrompt(args []string) string {
+	if c.config.Core.Rm.Permissive || c.option.Rm.interactiveMode() != interactiveOnce {
+		return ""
+	}
+
+	recursive := c.option.Rm.isRecursive()
+	if len(args) <= 3 && !recursive {
+		return ""
+	}
+
+	noun := "arguments"
+	if len(args) == 1 {
+		noun = "argument"
+	}
+	if recursive {
+		return fmt.Sprintf("remove %d %s recursively?", len(args), noun)
+	}
+	return fmt.Sprintf("remove %d %s?", len(args), noun)
+}
+
+// checkRemovable applies the rm rules for directories: a directory
+// requires -r, or -d if it is empty.
+func (c *CLI) checkRemovable(arg string, fi os.FileInfo, path string) error {
+	if c.config.Core.Rm.Permissive || !fi.IsDir() || c.option.Rm.isRecursive() {
+		return nil
+	}
+
+	if !c.option.Rm.Directory {
+		return fmt.Errorf("cannot remove %q: Is a directory", arg)
+	}
+
+	empty, err := isEmptyDir(path)
+	if err != nil {
+		return fmt.Errorf("cannot remove %q: %w", arg, err)
+	}
+	if !empty {
+		return fmt.Errorf("cannot remove %q: Directory not empty", arg)
+	}
+	return nil
+}
+
+// checkFileSystem applies --preserve-root=all and --one-file-system:
+// an argument must be on the same device as its parent, and a directory
+// must not contain a mount point of another file system.
+func (c *CLI) checkFileSystem(arg string, fi os.FileInfo, path string) error {
+	if c.option.Rm.PreserveRoot == "all" {
+		same, err := isOnSameDevice(path, filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("failed to check device: %w", err)
+		}
+		if !same {
+			return fmt.Errorf("skipping %q, since it's on a different device (--preserve-root=all is in effect)", arg)
+		}
+	}
+
+	if c.option.Rm.OneFileSystem && fi.IsDir() {
+		mount, err := findMountPoint(path)
+		if err != nil {
+			return fmt.Errorf("failed to check file system: %w", err)
+		}
+		if mount != "" {
+			return fmt.Errorf("refusing to remove %q, since %q is on a different device (--one-file-system is in effect)", arg, mount)
+		}
+	}
+
+	return nil
+}
+
+// isOnSameDevice checks if two paths are on the same device.
+// It returns true where devices cannot be compared.
+func isOnSameDevice(path1, path2 string) (bool, error) {
+	dev1, err := gomifs.Device(path1)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	dev2, err := gomifs.Device(path2)
+	if err != nil {
+		return false, err
+	}
+	return dev1 == dev2, nil
+}
+
+// findMountPoint returns the first directory under root that is on
+// a different device from root, or an empty string if there is none
+func findMountPoint(root string) (string, error) {
+	var found string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		same, err := isOnSameDevice(root, path)
+		if err != nil {
+			return err
+		}
+		if !same {
+			found = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+// confirmRemoval asks before moving each file to trash, like rm -i.
+// It returns false if the user declines.
+func (c *CLI) confirmRemoval(arg string, fi os.FileInfo) bool {
+	if c.config.Core.Rm.Permissive || c.option.Rm.interactiveMode() != interactiveAlways {
+		return true
+	}
+	return ui.Confirm(fmt.Sprintf("remove %s %q?", describeFileType(fi), arg))
+}
+
+// describeFileType returns the file type wording used by rm prompts
+func describeFileType(fi os.FileInfo) string {
+	switch {
+	case fi.IsDir():
+		return "directory"
+	case fi.Mode()&os.ModeSymlink != 0:
+		return "symbolic link"
+	case fi.Mode().IsRegular() && fi.Size() == 0:
+		return "regular empty file"
+	case fi.Mode().IsRegular():
+		return "regular file"
+	default:
+		return "file"
+	}
+}
+
+// isEmptyDir returns true if the directory has no entries
+func isEmptyDir(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	_, err = f.Readdirnames(1)
+	if errors.Is(err, io.EOF) {
+		return true, nil
+	}
+	return false, err
+}
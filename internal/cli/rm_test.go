@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/babarot/gomi/internal/config"
+)
+
+func TestInteractiveMode(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  RmOption
+		want interactive
+	}{
+		{"none", RmOption{}, interactiveNever},
+		{"-i", RmOption{Interactive: true}, interactiveAlways},
+		{"-I", RmOption{InteractiveOnce: true}, interactiveOnce},
+		{"-i -I", RmOption{Interactive: true, InteractiveOnce: true}, interactiveAlways},
+		{"-f -i", RmOption{Force: true, Interactive: true}, interactiveNever},
+		{"-f -I", RmOption{Force: true, InteractiveOnce: true}, interactiveNever},
+		{"--interactive=always", RmOption{InteractiveWhen: "always"}, interactiveAlways},
+		{"--interactive=once", RmOption{InteractiveWhen: "once"}, interactiveOnce},
+		{"--interactive=never", RmOption{InteractiveWhen: "never"}, interactiveNever},
+		{"--interactive=never -i", RmOption{InteractiveWhen: "never", Interactive: true}, interactiveAlways},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opt.interactiveMode(); got != tt.want {
+				t.Errorf("interactiveMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOncePrompt(t *testing.T) {
+	tests := []struct {
+		name       string
+		opt        RmOption
+		permissive bool
+		args       int
+		want       string
+	}{
+		{"without -I", RmOption{}, false, 5, ""},
+		{"-I with 3 files", RmOption{InteractiveOnce: true}, false, 3, ""},
+		{"-I with 4 files", RmOption{InteractiveOnce: true}, false, 4, "remove 4 arguments?"},
+		{"-I -r with 1 file", RmOption{InteractiveOnce: true, Recursive: true}, false, 1, "remove 1 argument recursively?"},
+		{"-I -R with 2 files", RmOption{InteractiveOnce: true, Recursive2: true}, false, 2, "remove 2 arguments recursively?"},
+		{"-I -f", RmOption{InteractiveOnce: true, Force: true}, false, 4, ""},
+		{"-i", RmOption{Interactive: true, Recursive: true}, false, 4, ""},
+		{"permissive", RmOption{InteractiveOnce: true}, true, 4, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CLI{config: &config.Config{}, option: Option{Rm: tt.opt}}
+			c.config.Core.Rm.Permissive = tt.permissive
+			args := make([]string, tt.args)
+			if got := c.oncePrompt(args); got != tt.want {
+				t.Errorf("oncePrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRemovable(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	full := filepath.Join(dir, "full")
+	file := filepath.Join(dir, "file")
+	for _, d := range []string{empty, full} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{file, filepath.Join(full, "file")} {
+		if err := os.WriteFile(f, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		opt        RmOption
+		permissive bool
+		wantErr    string // empty means no error
+	}{
+		{"file", file, RmOption{}, false, ""},
+		{"directory", empty, RmOption{}, false, "Is a directory"},
+		{"directory with -r", full, RmOption{Recursive: true}, false, ""},
+		{"directory with -R", full, RmOption{Recursive2: true}, false, ""},
+		{"empty directory with -d", empty, RmOption{Directory: true}, false, ""},
+		{"directory with -d", full, RmOption{Directory: true}, false, "Directory not empty"},
+		{"directory with -d -r", full, RmOption{Directory: true, Recursive: true}, false, ""},
+		{"directory with permissive", full, RmOption{}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CLI{config: &config.Config{}, option: Option{Rm: tt.opt}}
+			c.config.Core.Rm.Permissive = tt.permissive
+			fi, err := os.Lstat(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.checkRemovable(filepath.Base(tt.path), fi, tt.path)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("checkRemovable() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("checkRemovable() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckFileSystem(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for file systems")
+	}
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// A mount point under a small directory, so that walking its parent is quick
+	var mount string
+	for _, path := range []string{"/dev/pts", "/dev/shm"} {
+		if same, err := isOnSameDevice(path, filepath.Dir(path)); err == nil && !same {
+			mount = path
+			break
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		opt     RmOption
+		wantErr string // empty means no error
+	}{
+		{"same device", sub, RmOption{PreserveRoot: "all"}, ""},
+		{"no mount point", dir, RmOption{OneFileSystem: true}, ""},
+		{"different device", mount, RmOption{PreserveRoot: "all"}, "--preserve-root=all"},
+		{"different device without all", mount, RmOption{PreserveRoot: "root"}, ""},
+		{"mount point inside", filepath.Dir(mount), RmOption{OneFileSystem: true}, "--one-file-system"},
+		{"mount point inside without --one-file-system", filepath.Dir(mount), RmOption{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if mount == "" && !strings.HasPrefix(tt.path, dir) {
+				t.Skip("no mount point to test with")
+			}
+			c := &CLI{option: Option{Rm: tt.opt}}
+			fi, err := os.Lstat(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.checkFileSystem(tt.path, fi, tt.path)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("checkFileSystem() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("checkFileSystem() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/utils/shell"
+	"github.com/docker/go-units"
+	"github.com/k1LoW/duration"
+)
+
+// SelectorOption provides options to select files in the trash
+// by their age, size and original location
+type SelectorOption struct {
+	OlderThan  string `long:"older-than" value-name:"DURATION" description:"Select files deleted more than DURATION ago (e.g. 30d, 2w)"`
+	LargerThan string `long:"larger-than" value-name:"SIZE" description:"Select files larger than SIZE (e.g. 100MB, 1GB)"`
+	From       string `long:"from" value-name:"PATH" description:"Select files originally located under PATH"`
+}
+
+// selector is the parsed form of SelectorOption
+type selector struct {
+	olderThan  time.Duration
+	largerThan int64
+	from       string
+}
+
+// isEmpty returns true if no selector is specified
+func (o SelectorOption) isEmpty() bool {
+	return o.OlderThan == "" && o.LargerThan == "" && o.From == ""
+}
+
+// parse validates the selector options and converts them into a selector
+func (o SelectorOption) parse() (selector, error) {
+	var s selector
+
+	if o.OlderThan != "" {
+		d, err := duration.Parse(o.OlderThan)
+		if err != nil {
+			return s, fmt.Errorf("invalid --older-than value %q: %w", o.OlderThan, err)
+		}
+		s.olderThan = d
+	}
+
+	if o.LargerThan != "" {
+		size, err := units.FromHumanSize(o.LargerThan)
+		if err != nil {
+			return s, fmt.Errorf("invalid --larger-than value %q: %w", o.LargerThan, err)
+		}
+		s.largerThan = size
+	}
+
+	if o.From != "" {
+		expanded, err := shell.ExpandHome(o.From)
+		if err != nil {
+			return s, fmt.Errorf("invalid --from value %q: %w", o.From, err)
+		}
+		abs, err := filepath.Abs(expanded)
+		if err != nil {
+			return s, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		s.from = abs
+	}
+
+	return s, nil
+}
+
+// match reports whether the file satisfies all of the selectors
+func (s selector) match(file *trash.File) bool {
+	if s.olderThan > 0 && time.Since(file.DeletedAt) < s.olderThan {
+		return false
+	}
+
+	if s.from != "" {
+		path := filepath.Clean(file.OriginalPath)
+		if path != s.from && !strings.HasPrefix(path, s.from+string(filepath.Separator)) {
+			return false
+		}
+	}
+
+	if s.largerThan > 0 {
+		size, err := file.StoredSize()
+		if err != nil || size <= s.largerThan {
+			return false
+		}
+	}
+
+	return true
+}
+
+// selectFiles returns the files that satisfy all of the selectors
+func (s selector) selectFiles(files []*trash.File) []*trash.File {
+	var selected []*trash.File
+	for _, file := range files {
+		if s.match(file) {
+			selected = append(selected, file)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+)
+
+func TestSelectorMatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for selector")
+	}
+
+	file := &trash.File{
+		OriginalPath: "/home/user/src/project/main.go",
+		DeletedAt:    time.Now().Add(-48 * time.Hour),
+	}
+
+	tests := []struct {
+		name  string
+		opt   SelectorOption
+		match bool
+	}{
+		{"no selector", SelectorOption{}, true},
+		{"older than 1 day", SelectorOption{OlderThan: "1d"}, true},
+		{"older than 1 week", SelectorOption{OlderThan: "1w"}, false},
+		{"from parent dir", SelectorOption{From: "/home/user/src"}, true},
+		{"from exact path", SelectorOption{From: "/home/user/src/project/main.go"}, true},
+		{"from sibling prefix", SelectorOption{From: "/home/user/sr"}, false},
+		{"from other dir", SelectorOption{From: "/tmp"}, false},
+		{"combined", SelectorOption{OlderThan: "1d", From: "/home/user"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := tt.opt.parse()
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got := sel.match(file); got != tt.match {
+				t.Errorf("match() = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestSelectorParseError(t *testing.T) {
+	for _, opt := range []SelectorOption{
+		{OlderThan: "yesterday"},
+		{LargerThan: "huge"},
+	} {
+		if _, err := opt.parse(); err == nil {
+			t.Errorf("parse(%+v) expected error", opt)
+		}
+	}
+}
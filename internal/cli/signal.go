@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// interruption watches SIGINT and SIGTERM while files are moved to trash.
+// On the first signal, files being moved are completed and the others are
+// left in place. A second signal terminates gomi as usual, and the moves
+// it interrupts are recovered from the journal the next time gomi starts.
+type interruption struct {
+	sigs        chan os.Signal
+	done        chan struct{}
+	interrupted atomic.Bool
+}
+
+// watchInterruption starts watching signals until stop is called
+func watchInterruption() *interruption {
+	i := &interruption{
+		sigs: make(chan os.Signal, 1),
+		done: make(chan struct{}),
+	}
+	signal.Notify(i.sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-i.sigs:
+			i.interrupted.Store(true)
+			signal.Stop(i.sigs)
+			fmt.Fprintln(os.Stderr, "gomi: interrupted, finishing the files being moved (interrupt again to abort)")
+		case <-i.done:
+		}
+	}()
+	return i
+}
+
+// Interrupted reports whether a signal has been received
+func (i *interruption) Interrupted() bool {
+	return i.interrupted.Load()
+}
+
+// stop stops watching signals
+func (i *interruption) stop() {
+	signal.Stop(i.sigs)
+	close(i.done)
+}
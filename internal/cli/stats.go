@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dustin/go-humanize"
+)
+
+// StatsCommand shows a summary of the trash
+type StatsCommand struct{}
+
+// Stats prints the number of files and the space they take in each storage,
+// and how much space deduplication saves in the storages using it
+func (c *CLI) Stats() error {
+	slog.Debug("cli.stats started")
+	defer slog.Debug("cli.stats finished")
+
+	stats, err := c.manager.Stats()
+	if err != nil {
+		// The stats of the other storages are still worth printing
+		fmt.Fprintf(os.Stderr, "gomi: %v\n", err)
+	}
+
+	var files int
+	var size, saved int64
+	for _, st := range stats {
+		fmt.Printf("%s: %d file(s), %s\n", st.Type, st.Files, humanize.Bytes(uint64(st.Size)))
+		files += st.Files
+		size += st.Size
+		if d := st.Dedup; d != nil && d.Blobs > 0 {
+			fmt.Printf("  deduplication: %d file(s) share %d blob(s) of %s, saving %s\n",
+				d.Refs, d.Blobs, humanize.Bytes(uint64(d.Stored)), humanize.Bytes(uint64(d.Saved)))
+			saved += d.Saved
+		}
+	}
+	if len(stats) > 1 {
+		fmt.Printf("Total: %d file(s), %s\n", files, humanize.Bytes(uint64(size)))
+	}
+	if saved > 0 {
+		fmt.Printf("Deduplication saves %s, the trash takes %s on disk\n",
+			humanize.Bytes(uint64(saved)), humanize.Bytes(uint64(size-saved)))
+	}
+	return err
+}
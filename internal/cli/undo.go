@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash"
+)
+
+// UndoCommand restores every file moved to trash by a single gomi invocation
+type UndoCommand struct {
+	Run string `long:"run" value-name:"ID" description:"Undo the run with the given ID instead of the most recent one"`
+}
+
+// Undo restores all files trashed by the most recent run, or by the given run
+func (c *CLI) Undo() error {
+	slog.Debug("cli.undo started")
+	defer slog.Debug("cli.undo finished")
+
+	files, err := c.manager.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to list trash contents: %w", err)
+	}
+	files = c.filterFiles(files)
+
+	runID := c.option.Undo.Run
+	if runID == "" {
+		latest := latestRun(files)
+		if latest == nil {
+			return errors.New("no run to undo was found in the trash")
+		}
+		runID = latest.RunID
+	}
+
+	var targets []*trash.File
+	for _, file := range files {
+		if file.RunID == runID {
+			targets = append(targets, file)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no file in the trash belongs to run %q", runID)
+	}
+	slog.Debug("undo run", "run_id", runID, "len(files)", len(targets))
+
+	var failed []string
+	for _, file := range targets {
+		if err := c.restoreFileByPattern(file, file.OriginalPath); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to restore %s: %v\n", file.OriginalPath, err)
+			failed = append(failed, file.OriginalPath)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore files %v", failed)
+	}
+	return nil
+}
+
+// latestRun returns the most recently deleted file that has a run ID
+func latestRun(files []*trash.File) *trash.File {
+	var latest *trash.File
+	for _, file := range files {
+		if file.RunID == "" {
+			continue
+		}
+		if latest == nil || file.DeletedAt.After(latest.DeletedAt) {
+			latest = file
+		}
+	}
+	return latest
+}
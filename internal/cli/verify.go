@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash/checksum"
+)
+
+// VerifyCommand checks files in the trash against their checksums
+type VerifyCommand struct {
+	Selector SelectorOption `group:"Selector Options"`
+}
+
+// Verify checks that the files in the trash matching the given selectors (all of
+// them by default) are unchanged since they were trashed, by their checksums.
+// Files trashed without a checksum are counted but cannot be verified.
+func (c *CLI) Verify() error {
+	slog.Debug("cli.verify started")
+	defer slog.Debug("cli.verify finished")
+
+	sel, err := c.option.Verify.Selector.parse()
+	if err != nil {
+		return err
+	}
+
+	files, err := c.manager.ListAll()
+	if err != nil {
+		return fmt.Errorf("failed to list trash contents: %w", err)
+	}
+	targets := sel.selectFiles(files)
+
+	var ok, failed, unverified int
+	for _, file := range targets {
+		if file.Checksum == "" {
+			unverified++
+			continue
+		}
+		err := c.manager.Verify(file)
+		switch {
+		case err == nil:
+			ok++
+			if c.option.Rm.Verbose {
+				fmt.Printf("OK: %s (%s)\n", file.OriginalPath, file.TrashPath)
+			}
+		case errors.Is(err, checksum.ErrMismatch):
+			failed++
+			fmt.Fprintf(os.Stderr, "FAILED: %s (%s)\n", file.OriginalPath, file.TrashPath)
+		default:
+			failed++
+			fmt.Fprintf(os.Stderr, "FAILED: %s: %v\n", file.OriginalPath, err)
+		}
+	}
+
+	fmt.Printf("Verified %d file(s): %d ok, %d failed, %d without checksum\n",
+		ok+failed, ok, failed, unverified)
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) in the trash do not match their checksum", failed)
+	}
+	return nil
+}
@@ -34,6 +34,18 @@ type Core struct {
 	Restore RestoreConfig `yaml:"restore"`
 	Delete  DeleteConfig  `yaml:"delete"`
 
+	// Retention contains settings for expiring old files in the trash
+	Retention RetentionConfig `yaml:"retention"`
+
+	// Rm contains settings for rm compatible options
+	Rm RmConfig `yaml:"rm"`
+
+	// Protect lists rules that guard paths from being moved to trash
+	Protect []ProtectRule `yaml:"protect" validate:"dive"`
+
+	// DirectDelete lists rules for paths that are deleted without going to trash
+	DirectDelete []DirectDeleteRule `yaml:"direct_delete" validate:"dive"`
+
 	// Deprecated
 	TrashDir string `yaml:"trash_dir" validate:"deprecated"`
 }
@@ -48,6 +60,44 @@ type TrashConfig struct {
 
 	// GomiDir specifies the trash directory for legacy mode
 	GomiDir string `yaml:"gomi_dir" validate:"omitempty,validDirPath"`
+
+	// HomeDir specifies the home trash directory for XDG mode.
+	// If empty, defaults to $XDG_DATA_HOME/Trash (~/.local/share/Trash).
+	HomeDir string `yaml:"home_dir" validate:"omitempty,validDirPath"`
+
+	// PreservePaths mirrors original paths in the legacy store
+	// (e.g., ~/.gomi/tree/home/me/src/x.go) instead of grouping files by date
+	PreservePaths bool `yaml:"preserve_paths"`
+
+	// CreateExternal creates $topdir/.Trash-$uid on a writable mount when a file
+	// on that mount is trashed and it has no trash directory yet (XDG mode)
+	CreateExternal bool `yaml:"create_external"`
+
+	// Compression contains settings for compressing trashed files
+	Compression CompressionConfig `yaml:"compression"`
+
+	// Checksum is the algorithm used to record a checksum of every trashed file,
+	// which is verified on restore and by "gomi verify". Empty disables checksums.
+	Checksum string `yaml:"checksum" validate:"omitempty,oneof=sha256"`
+
+	// Dedup stores identical file contents once in the legacy store (~/.gomi/blobs),
+	// hardlinking every trashed copy to it
+	Dedup bool `yaml:"dedup"`
+}
+
+// CompressionConfig defines how files are compressed when moved to the legacy store.
+// Files are compressed one by one, and directories are archived with tar first.
+// Files are not compressed in the XDG trash, which other tools read as well.
+type CompressionConfig struct {
+	// Enable turns on compression (disabled by default)
+	Enable bool `yaml:"enable"`
+
+	// Format is the compression format: "zstd" (default) or "gzip"
+	Format string `yaml:"format" validate:"omitempty,oneof=zstd gzip"`
+
+	// MinSize is the size a file or directory must reach to be compressed (e.g., "1MB").
+	// Empty means any size.
+	MinSize string `yaml:"min_size" validate:"validSize|allowEmpty"`
 }
 
 // RestoreConfig defines settings for file restoration behavior
@@ -57,6 +107,20 @@ type RestoreConfig struct {
 
 	// Verbose enables detailed output during restore
 	Verbose bool `yaml:"verbose"`
+
+	// OnConflict is what to do when a file is restored onto an existing path:
+	// - "prompt": ask for a new name (default)
+	// - "rename": restore with a numbered suffix, keeping both
+	// - "overwrite": move the existing file to trash first
+	// - "skip": leave the file in trash
+	// - "merge": restore the entries of a directory into the existing directory
+	OnConflict string `yaml:"on_conflict" validate:"omitempty,oneof=prompt rename overwrite skip merge"`
+
+	// OnChecksumMismatch is what to do when a file does not match the checksum
+	// recorded when it was trashed:
+	// - "refuse": leave the file in trash (default)
+	// - "warn": restore it anyway with a warning
+	OnChecksumMismatch string `yaml:"on_checksum_mismatch" validate:"omitempty,oneof=refuse warn"`
 }
 
 // DeleteConfig defines settings for file permanent deletion behavior
@@ -64,6 +128,54 @@ type DeleteConfig struct {
 	Disable bool `yaml:"disable"`
 }
 
+// RetentionConfig defines how long files are kept in the trash.
+// When any limit is exceeded, the oldest files are permanently deleted first.
+type RetentionConfig struct {
+	// MaxAge is the maximum time a file is kept in the trash (e.g., "30d")
+	MaxAge string `yaml:"max_age" validate:"validDuration|allowEmpty"`
+
+	// MaxSize is the maximum total size of the trash (e.g., "10GB")
+	MaxSize string `yaml:"max_size" validate:"validSize|allowEmpty"`
+
+	// MaxItems is the maximum number of files kept in the trash
+	MaxItems int `yaml:"max_items" validate:"gte=0"`
+}
+
+// RmConfig defines how rm compatible options behave
+type RmConfig struct {
+	// Permissive ignores the rm semantics of -i, -I, -r and -d,
+	// so that directories are moved to trash without -r and nothing is prompted
+	Permissive bool `yaml:"permissive"`
+}
+
+// ProtectRule defines a rule to guard paths from being moved to trash.
+// Either Glob or Pattern must be set, and both are matched against absolute paths.
+type ProtectRule struct {
+	// Glob is a glob pattern (e.g., "~/.ssh/**", "**/.git")
+	Glob string `yaml:"glob" validate:"required_without=Pattern"`
+
+	// Pattern is a regular expression (e.g., "prod")
+	Pattern string `yaml:"pattern" validate:"validRegexp|allowEmpty"`
+
+	// Action is what to do when a path matches:
+	// - "refuse": never move the path to trash (default)
+	// - "confirm": require the name of the file to be typed
+	// - "warn": print a warning and move the path to trash
+	Action string `yaml:"action" validate:"omitempty,oneof=refuse confirm warn"`
+}
+
+// DirectDeleteRule defines disposable paths (e.g., node_modules) that are
+// permanently deleted instead of being moved to trash.
+type DirectDeleteRule struct {
+	// Glob is a glob pattern matched against absolute paths (e.g., "**/node_modules").
+	// A glob without a path separator is matched against the base name.
+	Glob string `yaml:"glob" validate:"required"`
+
+	// MinSize limits the rule to paths at least this large (e.g., "100MB").
+	// Empty means any size.
+	MinSize string `yaml:"min_size" validate:"validSize|allowEmpty"`
+}
+
 // UI holds all user interface related configurations
 type UI struct {
 	// Density controls the compactness of the UI (compact or spacious)
@@ -286,6 +398,8 @@ func (c *Config) validate() error {
 	_ = validate.RegisterValidation("validStrategy", validateStrategy)
 	_ = validate.RegisterValidation("allowEmpty", validateAllowEmpty)
 	_ = validate.RegisterValidation("validSize", validateSize)
+	_ = validate.RegisterValidation("validDuration", validateDuration)
+	_ = validate.RegisterValidation("validRegexp", validateRegexp)
 	_ = validate.RegisterValidation("validColorCode", validateColorCode)
 	_ = validate.RegisterValidation("deprecated", validateDeprecated)
 	_ = validate.RegisterValidation("validDirPath", validateDirPath)
@@ -303,6 +417,16 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// Warnings returns the settings that are valid but have no effect
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Core.Trash.Compression.Enable && c.Core.Trash.Strategy != "legacy" {
+		warnings = append(warnings,
+			"core.trash.compression.enable has no effect unless core.trash.strategy is \"legacy\", as files are never compressed in the XDG trash")
+	}
+	return warnings
+}
+
 // expandPaths expands all file paths in the configuration
 func (c *Config) expandPaths() error {
 	// Expand GomiDir path
@@ -314,6 +438,36 @@ func (c *Config) expandPaths() error {
 		c.Core.Trash.GomiDir = expanded
 	}
 
+	// Expand HomeDir path
+	if c.Core.Trash.HomeDir != "" {
+		expanded, err := shell.ExpandHome(c.Core.Trash.HomeDir)
+		if err != nil {
+			return fmt.Errorf("failed to expand HomeDir path: %w", err)
+		}
+		c.Core.Trash.HomeDir = expanded
+	}
+
+	// Expand protect globs
+	for i, rule := range c.Core.Protect {
+		if rule.Glob == "" {
+			continue
+		}
+		expanded, err := shell.ExpandHome(rule.Glob)
+		if err != nil {
+			return fmt.Errorf("failed to expand protect glob: %w", err)
+		}
+		c.Core.Protect[i].Glob = expanded
+	}
+
+	// Expand direct delete globs
+	for i, rule := range c.Core.DirectDelete {
+		expanded, err := shell.ExpandHome(rule.Glob)
+		if err != nil {
+			return fmt.Errorf("failed to expand direct_delete glob: %w", err)
+		}
+		c.Core.DirectDelete[i].Glob = expanded
+	}
+
 	return nil
 }
 
@@ -323,4 +477,8 @@ func (c *Config) setDefault() {
 	if c.UI.Style.DeletionDialog == "" {
 		c.UI.Style.DeletionDialog = "205"
 	}
+
+	if c.Core.Trash.Compression.Format == "" {
+		c.Core.Trash.Compression.Format = "zstd"
+	}
 }
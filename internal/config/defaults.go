@@ -15,11 +15,20 @@ func NewDefaultConfig() *Config {
 				// Default to composite strategy
 				Strategy: "auto",
 				GomiDir:  filepath.Join(homedir, ".gomi"),
+				Compression: CompressionConfig{
+					Enable:  false,
+					Format:  "zstd",
+					MinSize: "1MB",
+				},
+				Checksum: "",
+				Dedup:    false,
 			},
 			HomeFallback: true,
 			Restore: RestoreConfig{
-				Confirm: true,
-				Verbose: true,
+				Confirm:            true,
+				Verbose:            true,
+				OnConflict:         "prompt",
+				OnChecksumMismatch: "refuse",
 			},
 			Delete: DeleteConfig{
 				Disable: false,
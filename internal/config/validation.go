@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/k1LoW/duration"
 )
 
 // validateStrategy validates the trash strategy value
@@ -30,6 +31,18 @@ func validateSize(fl validator.FieldLevel) bool {
 	return re.MatchString(value)
 }
 
+// validateDuration validates the duration format (e.g., "30d", "2w")
+func validateDuration(fl validator.FieldLevel) bool {
+	d, err := duration.Parse(fl.Field().String())
+	return err == nil && d > 0
+}
+
+// validateRegexp validates the field is a valid regular expression
+func validateRegexp(fl validator.FieldLevel) bool {
+	_, err := regexp.Compile(fl.Field().String())
+	return err == nil
+}
+
 // validateColorCode checks if the field contains a valid hex color code.
 func validateColorCode(fl validator.FieldLevel) bool {
 	value := fl.Field().String()
@@ -0,0 +1,226 @@
+// Package archive packs files in the trash into a portable archive,
+// so that they can be put back in the trash or restored on another host.
+//
+// An archive is a compressed tar containing manifest.json first,
+// followed by the file of each entry under files/<index>.
+package archive
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/compress"
+	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/google/uuid"
+)
+
+const (
+	manifestName    = "manifest.json"
+	filesDir        = "files"
+	manifestVersion = 1
+)
+
+// Manifest describes the files in an archive
+type Manifest struct {
+	Version int      `json:"version"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Entry is the metadata of a file in an archive
+type Entry struct {
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"original_path"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	RunID        string    `json:"run_id,omitempty"`
+	Storage      string    `json:"storage"`
+	IsDir        bool      `json:"is_dir"`
+	Size         int64     `json:"size"`
+
+	// Compression is how the file is compressed, as it was in the trash
+	Compression string `json:"compression,omitempty"`
+
+	// Metadata is the ownership, mode, times and extended attributes of the file
+	// before it was moved to trash
+	Metadata *fs.Metadata `json:"metadata,omitempty"`
+
+	// Checksum is the checksum of the file as it was in the trash
+	Checksum string `json:"checksum,omitempty"`
+
+	// Path is where the file is in the archive.
+	// Once extracted, it is the path of the file on disk.
+	Path string `json:"path"`
+}
+
+// File returns the entry as a file stored at its Path
+func (e *Entry) File() *trash.File {
+	return &trash.File{
+		Name:         e.Name,
+		OriginalPath: e.OriginalPath,
+		TrashPath:    e.Path,
+		DeletedAt:    e.DeletedAt,
+		RunID:        e.RunID,
+		IsDir:        e.IsDir,
+		Size:         e.Size,
+		Compression:  e.Compression,
+		Metadata:     e.Metadata,
+		Checksum:     e.Checksum,
+	}
+}
+
+// FormatOf returns the compression format of an archive from its name
+func FormatOf(name string) (compress.Format, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return compress.Zstd, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return compress.Gzip, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format: %s (use .tar.zst or .tar.gz)", filepath.Base(name))
+	}
+}
+
+// Write packs the files into an archive at dst, which must not exist.
+// The archive is written to a temporary file first, so dst only appears once complete.
+func Write(dst string, files []*trash.File) (err error) {
+	format, err := FormatOf(dst)
+	if err != nil {
+		return err
+	}
+
+	manifest := Manifest{Version: manifestVersion}
+	for i, file := range files {
+		var storage string
+		if s := file.GetStorage(); s != nil {
+			storage = s.Info().Type.String()
+		}
+		manifest.Entries = append(manifest.Entries, &Entry{
+			Name:         file.Name,
+			OriginalPath: file.OriginalPath,
+			DeletedAt:    file.DeletedAt,
+			RunID:        file.RunID,
+			Storage:      storage,
+			IsDir:        file.IsDir,
+			Size:         file.Size,
+			Compression:  file.Compression,
+			Metadata:     file.Metadata,
+			Checksum:     file.Checksum,
+			Path:         path.Join(filesDir, strconv.Itoa(i)),
+		})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s: %w", dst, os.ErrExist)
+	}
+	tmpPath := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.%s.tmp", filepath.Base(dst), uuid.New().String()))
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	w, err := compress.NewWriter(f, format)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+		Format:  tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     filesDir + "/",
+		Typeflag: tar.TypeDir,
+		Mode:     0700,
+		ModTime:  time.Now(),
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	for i, file := range files {
+		if err := compress.AddTar(tw, file.TrashPath, manifest.Entries[i].Path); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", file.OriginalPath, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, dst)
+}
+
+// Extract extracts the archive at src into the existing empty directory dir,
+// and returns its manifest with the paths of the entries on disk
+func Extract(src, dir string) (*Manifest, error) {
+	format, err := FormatOf(src)
+	if err != nil {
+		return nil, err
+	}
+	r, err := compress.Open(src, compress.Method{Format: format})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if err := compress.ExtractTar(r, dir); err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", src, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("not a gomi archive: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version: %d", manifest.Version)
+	}
+
+	for _, entry := range manifest.Entries {
+		name := filepath.FromSlash(entry.Path)
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("invalid entry in manifest: %q", entry.Path)
+		}
+		entry.Path = filepath.Join(dir, name)
+		if _, err := os.Lstat(entry.Path); err != nil {
+			return nil, errors.Join(fmt.Errorf("missing entry in archive: %q", entry.OriginalPath), err)
+		}
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,144 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+)
+
+func TestWriteExtract(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes differ on windows")
+	}
+
+	trashDir := t.TempDir()
+	file := filepath.Join(trashDir, "a.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(trashDir, "d")
+	if err := os.MkdirAll(filepath.Join(dir, "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "e", "f"), []byte("world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	files := []*trash.File{
+		{Name: "a.txt", OriginalPath: "/home/me/a.txt", TrashPath: file, DeletedAt: deletedAt, RunID: "run1", Size: 5},
+		{Name: "d", OriginalPath: "/home/me/d", TrashPath: dir, DeletedAt: deletedAt, IsDir: true, Size: 5},
+	}
+
+	tests := []struct {
+		name string
+		dst  string
+	}{
+		{name: "zstd", dst: "trash.tar.zst"},
+		{name: "gzip", dst: "trash.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), tt.dst)
+			if err := Write(dst, files); err != nil {
+				t.Fatal(err)
+			}
+			if err := Write(dst, files); err == nil {
+				t.Error("overwrote an existing archive")
+			}
+
+			manifest, err := Extract(dst, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(manifest.Entries) != len(files) {
+				t.Fatalf("got %d entries, want %d", len(manifest.Entries), len(files))
+			}
+
+			got := manifest.Entries[0].File()
+			if got.OriginalPath != "/home/me/a.txt" || !got.DeletedAt.Equal(deletedAt) || got.RunID != "run1" {
+				t.Errorf("entry = %+v", got)
+			}
+			if data, err := os.ReadFile(got.TrashPath); err != nil || string(data) != "hello" {
+				t.Errorf("content of %s = %q, %v", got.TrashPath, data, err)
+			}
+
+			got = manifest.Entries[1].File()
+			if !got.IsDir {
+				t.Errorf("%s is not a directory", got.OriginalPath)
+			}
+			if data, err := os.ReadFile(filepath.Join(got.TrashPath, "e", "f")); err != nil || string(data) != "world" {
+				t.Errorf("content of %s = %q, %v", got.TrashPath, data, err)
+			}
+		})
+	}
+}
+
+func TestExtractSymlinkTraversal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+
+	// files/0 points outside, and files/0/pwned would be written through it
+	victim := t.TempDir()
+	src := filepath.Join(t.TempDir(), "evil.tar.gz")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	entries := []struct {
+		hdr  tar.Header
+		data string
+	}{
+		{hdr: tar.Header{Name: filesDir + "/", Typeflag: tar.TypeDir, Mode: 0700}},
+		{hdr: tar.Header{Name: filesDir + "/0", Typeflag: tar.TypeSymlink, Linkname: victim}},
+		{hdr: tar.Header{Name: filesDir + "/0/pwned", Typeflag: tar.TypeReg, Mode: 0600, Size: 5}, data: "pwned"},
+	}
+	for _, e := range entries {
+		if err := tw.WriteHeader(&e.hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gw, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Extract(src, t.TempDir()); err == nil {
+		t.Error("extracted an entry through a symbolic link")
+	}
+	if _, err := os.Lstat(filepath.Join(victim, "pwned")); !os.IsNotExist(err) {
+		t.Errorf("wrote outside of the extraction directory: %v", err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "trash.tar.zst"},
+		{name: "trash.tzst"},
+		{name: "trash.tar.gz"},
+		{name: "trash.tgz"},
+		{name: "trash.zip", wantErr: true},
+		{name: "trash", wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := FormatOf(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("FormatOf(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
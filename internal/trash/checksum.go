@@ -0,0 +1,68 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash/checksum"
+)
+
+// MismatchPolicy is what Restore does with a file that does not match its checksum
+type MismatchPolicy string
+
+const (
+	// MismatchRefuse leaves the file in trash and fails (default)
+	MismatchRefuse MismatchPolicy = "refuse"
+
+	// MismatchWarn restores the file and prints a warning
+	MismatchWarn MismatchPolicy = "warn"
+
+	// MismatchIgnore restores the file without verifying it
+	MismatchIgnore MismatchPolicy = "ignore"
+)
+
+// Sum returns the checksum to record for the file at path as it is stored in trash,
+// or an empty string if checksums are disabled or it cannot be computed
+func (c Config) Sum(path string) string {
+	if c.Checksum == "" {
+		return ""
+	}
+	sum, err := checksum.Sum(path, checksum.Algorithm(c.Checksum))
+	if err != nil {
+		slog.Warn("failed to compute checksum", "path", path, "error", err)
+		return ""
+	}
+	return sum
+}
+
+// Verify checks the file in trash against the checksum recorded when it was trashed.
+// It returns nil for files trashed without a checksum, and an error wrapping
+// checksum.ErrMismatch if the file has changed since.
+func (m *Manager) Verify(file *File) error {
+	return verify(file)
+}
+
+func verify(file *File) error {
+	if file.Checksum == "" {
+		return nil
+	}
+	return checksum.Verify(file.TrashPath, file.Checksum)
+}
+
+// VerifyBeforeRestore verifies a file about to be restored following ChecksumMismatch.
+// It returns an error if the file must not be restored, which wraps checksum.ErrMismatch
+// if the file has changed since it was trashed.
+func (c Config) VerifyBeforeRestore(file *File) error {
+	if c.ChecksumMismatch == MismatchIgnore {
+		return nil
+	}
+	err := verify(file)
+	if errors.Is(err, checksum.ErrMismatch) && c.ChecksumMismatch == MismatchWarn {
+		slog.Warn("restoring file not matching its checksum", "path", file.TrashPath, "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return err
+}
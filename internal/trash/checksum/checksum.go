@@ -0,0 +1,141 @@
+// Package checksum computes checksums of files and directories in the trash,
+// so that their integrity can be verified before they are restored.
+//
+// A checksum is recorded as "<algorithm>:<hex digest>". A directory is hashed
+// as a Merkle tree: its digest covers the sorted names, types and digests of
+// its entries, so that any change in the tree changes the digest of the root.
+// Modes, owners and times are not covered.
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Algorithm is a hash algorithm
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+)
+
+// ErrMismatch is returned when a file does not match its checksum
+var ErrMismatch = errors.New("checksum mismatch")
+
+// ParseAlgorithm returns the algorithm named s
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch a := Algorithm(s); a {
+	case SHA256:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown checksum algorithm: %q", s)
+	}
+}
+
+// new returns a hash of the algorithm. Algorithms are validated by ParseAlgorithm,
+// and SHA256 is the only one so far.
+func (a Algorithm) new() hash.Hash {
+	return sha256.New()
+}
+
+// Sum returns the checksum of the file or directory at path.
+// Symbolic links are not followed.
+func Sum(path string, algo Algorithm) (string, error) {
+	if _, err := ParseAlgorithm(string(algo)); err != nil {
+		return "", err
+	}
+	digest, err := algo.digest(path)
+	if err != nil {
+		return "", err
+	}
+	return string(algo) + ":" + hex.EncodeToString(digest), nil
+}
+
+// Verify checks that the file or directory at path matches the checksum.
+// It returns an error wrapping ErrMismatch if it does not.
+func Verify(path, sum string) error {
+	name, _, ok := strings.Cut(sum, ":")
+	if !ok {
+		return fmt.Errorf("invalid checksum: %q", sum)
+	}
+	algo, err := ParseAlgorithm(name)
+	if err != nil {
+		return err
+	}
+	got, err := Sum(path, algo)
+	if err != nil {
+		return err
+	}
+	if got != sum {
+		return fmt.Errorf("%w: %s is %s, expected %s", ErrMismatch, path, got, sum)
+	}
+	return nil
+}
+
+// Each kind of node is hashed with its own prefix, so that a file
+// cannot have the digest of a directory or a link
+const (
+	blobPrefix = "blob\x00"
+	linkPrefix = "link\x00"
+	treePrefix = "tree\x00"
+)
+
+func (a Algorithm) digest(path string) ([]byte, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	h := a.new()
+
+	switch {
+	case fi.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		io.WriteString(h, blobPrefix)
+		if _, err := io.Copy(h, f); err != nil {
+			return nil, err
+		}
+
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(h, linkPrefix+target)
+
+	case fi.IsDir():
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+		io.WriteString(h, treePrefix)
+		for _, entry := range entries {
+			child, err := a.digest(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			// Names cannot contain NUL, so entries cannot be confused with each other
+			io.WriteString(h, entry.Name()+"\x00")
+			h.Write(child)
+		}
+
+	default:
+		// Special files such as fifos and sockets have no content
+		fmt.Fprintf(h, "special\x00%s", fi.Mode().Type())
+	}
+	return h.Sum(nil), nil
+}
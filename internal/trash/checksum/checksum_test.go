@@ -0,0 +1,121 @@
+package checksum
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(t *testing.T, root string)
+		mismatch bool
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, root string) {},
+		},
+		{
+			name: "content",
+			change: func(t *testing.T, root string) {
+				write(t, filepath.Join(root, "sub", "b"), "B")
+			},
+			mismatch: true,
+		},
+		{
+			name: "renamed",
+			change: func(t *testing.T, root string) {
+				if err := os.Rename(filepath.Join(root, "a"), filepath.Join(root, "c")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			mismatch: true,
+		},
+		{
+			name: "added",
+			change: func(t *testing.T, root string) {
+				write(t, filepath.Join(root, "sub", "new"), "")
+			},
+			mismatch: true,
+		},
+		{
+			name: "mode and times",
+			change: func(t *testing.T, root string) {
+				if err := os.Chmod(filepath.Join(root, "a"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(filepath.Join(root, "sub"), time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			write(t, filepath.Join(root, "a"), "a")
+			write(t, filepath.Join(root, "sub", "b"), "b")
+
+			sum, err := Sum(root, SHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(t, root)
+
+			err = Verify(root, sum)
+			if tt.mismatch != errors.Is(err, ErrMismatch) {
+				t.Errorf("Verify() error = %v, want mismatch %v", err, tt.mismatch)
+			}
+			if !tt.mismatch && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSumKinds(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for symbolic links")
+	}
+
+	// A file, a link and a directory with the same content have different checksums
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "file"), "target")
+	if err := os.Symlink("target", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]string)
+	for _, name := range []string{"file", "link", "dir"} {
+		sum, err := Sum(filepath.Join(dir, name), SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := seen[sum]; ok {
+			t.Errorf("%s and %s have the same checksum", name, other)
+		}
+		seen[sum] = name
+	}
+
+	if err := Verify(filepath.Join(dir, "file"), "md5:00"); err == nil {
+		t.Error("Verify() with an unknown algorithm succeeded")
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
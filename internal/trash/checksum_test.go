@@ -0,0 +1,48 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/babarot/gomi/internal/trash/checksum"
+)
+
+func TestVerifyBeforeRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a")
+	if err := os.WriteFile(path, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := checksum.Sum(path, checksum.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intact := &File{TrashPath: path, Checksum: sum}
+	changed := &File{TrashPath: path, Checksum: "sha256:" + strings.Repeat("0", 64)}
+	unknown := &File{TrashPath: path}
+
+	tests := []struct {
+		policy   MismatchPolicy
+		file     *File
+		mismatch bool
+	}{
+		{MismatchRefuse, intact, false},
+		{MismatchRefuse, unknown, false},
+		{MismatchRefuse, changed, true},
+		{"", changed, true},
+		{MismatchWarn, changed, false},
+		{MismatchIgnore, changed, false},
+	}
+	for _, tt := range tests {
+		c := Config{ChecksumMismatch: tt.policy}
+		err := c.VerifyBeforeRestore(tt.file)
+		if errors.Is(err, checksum.ErrMismatch) != tt.mismatch {
+			t.Errorf("policy %q, checksum %q: error = %v, want mismatch %v", tt.policy, tt.file.Checksum, err, tt.mismatch)
+		}
+		if !tt.mismatch && err != nil {
+			t.Errorf("policy %q: unexpected error: %v", tt.policy, err)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+// Package compress compresses files and directories stored in the trash.
+// A file is compressed as is, and a directory is archived with tar first.
+package compress
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is a compression format
+type Format string
+
+const (
+	Zstd Format = "zstd"
+	Gzip Format = "gzip"
+)
+
+const tarPrefix = "tar+"
+
+// Method describes how a file in the trash is compressed.
+// It is recorded as "zstd" for a file and "tar+zstd" for a directory.
+type Method struct {
+	Format Format
+
+	// Tar is true if the content is a tar archive of a directory
+	Tar bool
+}
+
+func (m Method) String() string {
+	if m.Tar {
+		return tarPrefix + string(m.Format)
+	}
+	return string(m.Format)
+}
+
+// ParseMethod parses a method recorded by Method.String
+func ParseMethod(s string) (Method, error) {
+	m := Method{Format: Format(strings.TrimPrefix(s, tarPrefix))}
+	m.Tar = strings.HasPrefix(s, tarPrefix)
+	switch m.Format {
+	case Zstd, Gzip:
+		return m, nil
+	default:
+		return Method{}, fmt.Errorf("unknown compression method: %q", s)
+	}
+}
+
+// Compressible reports whether the file can be compressed.
+// Only regular files and directories are compressed.
+func Compressible(fi os.FileInfo) bool {
+	return fi.Mode().IsRegular() || fi.IsDir()
+}
+
+// NewWriter returns a writer compressing to w with the format
+func NewWriter(w io.Writer, format Format) (io.WriteCloser, error) {
+	switch format {
+	case Zstd:
+		return zstd.NewWriter(w)
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown compression format: %q", format)
+	}
+}
+
+// NewReader returns a reader decompressing r with the format
+func NewReader(r io.Reader, format Format) (io.ReadCloser, error) {
+	switch format {
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unknown compression format: %q", format)
+	}
+}
+
+// Compress writes src compressed with the format to dst, which must not exist.
+// A compressed file keeps the mode and modification time of src.
+// src is left untouched, and dst is removed if compression fails.
+func Compress(src, dst string, format Format) (m Method, err error) {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return m, err
+	}
+	if !Compressible(fi) {
+		return m, fmt.Errorf("cannot compress %s: not a regular file or directory", src)
+	}
+	m = Method{Format: format, Tar: fi.IsDir()}
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return m, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(dst)
+		}
+	}()
+
+	w, err := NewWriter(f, format)
+	if err != nil {
+		return m, err
+	}
+	if m.Tar {
+		err = writeTar(w, src)
+	} else {
+		err = copyFile(w, src)
+	}
+	if err != nil {
+		w.Close()
+		return m, err
+	}
+	if err := w.Close(); err != nil {
+		return m, err
+	}
+	if err := f.Sync(); err != nil {
+		return m, err
+	}
+	if err := f.Close(); err != nil {
+		return m, err
+	}
+
+	if !m.Tar {
+		if err := os.Chmod(dst, fi.Mode().Perm()); err != nil {
+			return m, err
+		}
+		if err := os.Chtimes(dst, fi.ModTime(), fi.ModTime()); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+func copyFile(w io.Writer, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// writeTar archives the directory root into w.
+// Entry names are relative to root, and root itself is stored as ".".
+func writeTar(w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+	if err := AddTar(tw, root, "."); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// AddTar adds the file or directory src to the archive as name.
+// Entries of a directory are named relative to it under name.
+func AddTar(tw *tar.Writer, src, name string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case !Compressible(fi):
+			// Special files such as sockets and fifos cannot be restored from an archive
+			return fmt.Errorf("cannot archive %s: unsupported file type", path)
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return fmt.Errorf("cannot archive %s: %w", path, err)
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		// PAX keeps sub-second modification times
+		hdr.Format = tar.FormatPAX
+		hdr.Name = pathpkg.Join(name, filepath.ToSlash(rel))
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			return copyFile(tw, path)
+		}
+		return nil
+	})
+}
+
+// Open returns a reader of the decompressed content of src.
+// For a directory, the content is a tar archive.
+func Open(src string, m Method) (io.ReadCloser, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f, m.Format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+// readCloser closes the decompressor and the underlying file together
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ReadDir returns the top-level entries of a compressed directory
+// without extracting it
+func ReadDir(src string, m Method) ([]fs.FileInfo, error) {
+	if !m.Tar {
+		return nil, fmt.Errorf("%s is not a compressed directory", src)
+	}
+	r, err := Open(src, m)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []fs.FileInfo
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(hdr.Name, "/")
+		if name == "." || strings.Contains(name, "/") {
+			continue
+		}
+		entries = append(entries, hdr.FileInfo())
+	}
+}
+
+// Decompress extracts src into dst, which must not exist.
+// dst is removed if decompression fails.
+func Decompress(src, dst string, m Method) error {
+	r, err := Open(src, m)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if m.Tar {
+		if err := os.Mkdir(dst, 0700); err != nil {
+			return err
+		}
+		if err := ExtractTar(r, dst); err != nil {
+			os.RemoveAll(dst)
+			return err
+		}
+		return nil
+	}
+
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err := errors.Join(err, f.Close()); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	if err := os.Chmod(dst, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+// ExtractTar extracts a tar archive written by AddTar into the existing root.
+// Directories must precede their entries in the archive, and entries under
+// a symbolic link are refused so that nothing is written outside of root.
+func ExtractTar(r io.Reader, root string) error {
+	// Modes and times of directories are set at the end,
+	// since extracting their entries would change them
+	var dirs []*tar.Header
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/"))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid entry in archive: %q", hdr.Name)
+		}
+		if err := checkParents(root, name); err != nil {
+			return fmt.Errorf("invalid entry in archive: %q: %w", hdr.Name, err)
+		}
+		path := filepath.Join(root, name)
+		mode := hdr.FileInfo().Mode()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if name != "." {
+				if err := os.Mkdir(path, 0700); err != nil {
+					return err
+				}
+			}
+			dirs = append(dirs, hdr)
+			continue
+		case tar.TypeReg:
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			// The mode given to OpenFile is masked by umask
+			if err := os.Chmod(path, mode.Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+			continue
+		default:
+			return fmt.Errorf("unsupported entry in archive: %q", hdr.Name)
+		}
+		if err := os.Chtimes(path, hdr.ModTime, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		hdr := dirs[i]
+		path := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/")))
+		if err := os.Chmod(path, hdr.FileInfo().Mode().Perm()); err != nil {
+			return err
+		}
+		if err := os.Chtimes(path, hdr.ModTime, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkParents returns an error unless every parent of the entry name under root
+// is a directory. Symbolic links extracted earlier are not followed, as they
+// could point anywhere.
+func checkParents(root, name string) error {
+	dir := root
+	for _, part := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package compress
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCompressFile(t *testing.T) {
+	for _, format := range []Format{Zstd, Gzip} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "app.log")
+			content := []byte("line 1\nline 2\n")
+			if err := os.WriteFile(src, content, 0640); err != nil {
+				t.Fatal(err)
+			}
+
+			blob := filepath.Join(dir, "blob")
+			m, err := Compress(src, blob, format)
+			if err != nil {
+				t.Fatalf("Compress() error = %v", err)
+			}
+			if m.String() != string(format) {
+				t.Errorf("Method = %q, want %q", m, format)
+			}
+
+			r, err := Open(blob, m)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			got, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || string(got) != string(content) {
+				t.Errorf("Open() content = %q, %v, want %q", got, err, content)
+			}
+
+			dst := filepath.Join(dir, "restored.log")
+			if err := Decompress(blob, dst, m); err != nil {
+				t.Fatalf("Decompress() error = %v", err)
+			}
+			got, _ = os.ReadFile(dst)
+			if string(got) != string(content) {
+				t.Errorf("restored content = %q, want %q", got, content)
+			}
+			if runtime.GOOS != "windows" {
+				if fi, _ := os.Stat(dst); fi.Mode().Perm() != 0640 {
+					t.Errorf("restored mode = %v, want 0640", fi.Mode().Perm())
+				}
+			}
+
+			if err := Decompress(blob, dst, m); err == nil {
+				t.Error("Decompress() to an existing path should fail")
+			}
+			if got, _ := os.ReadFile(dst); string(got) != string(content) {
+				t.Error("Decompress() must not touch an existing path")
+			}
+		})
+	}
+}
+
+func TestCompressDir(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "b.txt"), []byte("b"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	blob := filepath.Join(dir, "blob")
+	m, err := Compress(src, blob, Zstd)
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	if m.String() != "tar+zstd" {
+		t.Errorf("Method = %q, want %q", m, "tar+zstd")
+	}
+	parsed, err := ParseMethod(m.String())
+	if err != nil || parsed != m {
+		t.Errorf("ParseMethod(%q) = %v, %v", m, parsed, err)
+	}
+
+	entries, err := ReadDir(blob, m)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "b.txt" || names[1] != "sub" {
+		t.Errorf("ReadDir() = %v, want [b.txt sub]", names)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if err := Decompress(blob, dst, m); err != nil {
+		t.Fatalf("Decompress() error = %v", err)
+	}
+	for name, want := range map[string]string{"sub/a.txt": "a", "b.txt": "b"} {
+		got, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
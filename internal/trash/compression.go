@@ -0,0 +1,182 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/babarot/gomi/internal/trash/compress"
+	gomifs "github.com/babarot/gomi/internal/utils/fs"
+	"github.com/docker/go-units"
+	"github.com/google/uuid"
+)
+
+// Compressed is a compressed copy of a file being moved to trash.
+// It is written next to its destination and committed once the
+// metadata of the file has been saved.
+type Compressed struct {
+	// Method is how the file is compressed
+	Method compress.Method
+
+	// Size is the size of the original file
+	Size int64
+
+	// Checksum is the checksum of the compressed file, empty if checksums are disabled
+	Checksum string
+
+	// tmpPath is where the compressed copy is written
+	tmpPath string
+}
+
+// Compress compresses src into a temporary file in dir when compression is
+// enabled and src reaches the size threshold. It returns nil if src should
+// be moved to trash as is, including when compression fails.
+func (c Config) Compress(src, dir string) *Compressed {
+	if !c.UseCompression {
+		return nil
+	}
+
+	fi, err := os.Lstat(src)
+	if err != nil || !compress.Compressible(fi) {
+		return nil
+	}
+
+	size, err := gomifs.DirSize(src)
+	if err != nil {
+		slog.Warn("failed to get size, skip compression", "path", src, "error", err)
+		return nil
+	}
+	if c.Compression.MinSize != "" {
+		minSize, err := units.FromHumanSize(c.Compression.MinSize)
+		if err == nil && size < minSize {
+			return nil
+		}
+	}
+
+	format := compress.Format(c.Compression.Format)
+	if format == "" {
+		format = compress.Zstd
+	}
+
+	tmpPath := filepath.Join(dir, fmt.Sprintf(".%s.%s.tmp", filepath.Base(src), uuid.New().String()))
+	m, err := compress.Compress(src, tmpPath, format)
+	if err != nil {
+		slog.Warn("failed to compress, fallback to move as is", "path", src, "error", err)
+		return nil
+	}
+
+	slog.Debug("compressed", "path", src, "method", m.String(), "size", size)
+	return &Compressed{
+		Method:   m,
+		Size:     size,
+		Checksum: c.Sum(tmpPath),
+		tmpPath:  tmpPath,
+	}
+}
+
+// Commit moves the compressed copy to dst in the trash.
+// The original file is left for the caller to remove.
+func (c *Compressed) Commit(dst string) error {
+	if err := os.Rename(c.tmpPath, dst); err != nil {
+		c.Discard()
+		return fmt.Errorf("failed to move compressed file to trash: %w", err)
+	}
+	return nil
+}
+
+// Discard removes the compressed copy
+func (c *Compressed) Discard() {
+	os.Remove(c.tmpPath)
+}
+
+// Decompress extracts a compressed file from trash to dst and removes it from trash
+func Decompress(file *File, dst string) error {
+	return DecompressCheckpoint(file, dst, nil)
+}
+
+// DecompressCheckpoint is Decompress calling decompressed, if not nil, once dst
+// is complete and before the file is removed from trash.
+// If decompressed fails, dst is removed and the file is left in trash.
+func DecompressCheckpoint(file *File, dst string, decompressed func() error) error {
+	m, err := compress.ParseMethod(file.Compression)
+	if err != nil {
+		return err
+	}
+	if err := compress.Decompress(file.TrashPath, dst, m); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w: %s", ErrFileExists, dst)
+		}
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	if decompressed != nil {
+		if err := decompressed(); err != nil {
+			os.RemoveAll(dst)
+			return err
+		}
+	}
+	return os.Remove(file.TrashPath)
+}
+
+// IsCompressed returns true if the file is compressed in the trash
+func (f *File) IsCompressed() bool {
+	return f.Compression != ""
+}
+
+// Open returns a reader of the content of the file, which is
+// decompressed on the fly if the file is compressed
+func (f *File) Open() (io.ReadCloser, error) {
+	if !f.IsCompressed() {
+		return os.Open(f.TrashPath)
+	}
+	m, err := compress.ParseMethod(f.Compression)
+	if err != nil {
+		return nil, err
+	}
+	if m.Tar {
+		return nil, fmt.Errorf("%s is a compressed directory", f.Name)
+	}
+	return compress.Open(f.TrashPath, m)
+}
+
+// ReadDir returns the top-level entries of the directory,
+// reading them from the archive if the directory is compressed
+func (f *File) ReadDir() ([]fs.FileInfo, error) {
+	if !f.IsCompressed() {
+		entries, err := os.ReadDir(f.TrashPath)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]fs.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	}
+	m, err := compress.ParseMethod(f.Compression)
+	if err != nil {
+		return nil, err
+	}
+	return compress.ReadDir(f.TrashPath, m)
+}
+
+// StoredSize returns the size the file takes in the trash.
+// A directory is walked unless its size is already known by the storage.
+func (f *File) StoredSize() (int64, error) {
+	if f.storedSize > 0 {
+		return f.storedSize, nil
+	}
+	return gomifs.DirSize(f.TrashPath)
+}
+
+// SetStoredSize records the size the file takes in the trash
+func (f *File) SetStoredSize(size int64) {
+	f.storedSize = size
+}
@@ -26,7 +26,8 @@ type Config struct {
 	// mapping between user configuration and internal implementation.
 	Type StorageType
 
-	// HomeTrashDir specifies a custom home trash directory
+	// HomeTrashDir specifies a custom home trash directory used by the XDG storage
+	// instead of $XDG_DATA_HOME/Trash (e.g., on a large scratch volume)
 	HomeTrashDir string
 
 	// HomeFallback enables fallback to home trash when external trash fails
@@ -35,21 +36,48 @@ type Config struct {
 	// ForceHomeTrash forces using home trash even for external devices
 	ForceHomeTrash bool
 
+	// CreateExternalTrash creates a trash directory on the mount point of a file
+	// when none exists there, instead of copying the file to home trash
+	CreateExternalTrash bool
+
 	// SkipMountPointFind skips finding mount points for external trash dirs
 	SkipMountPointFind bool
 
 	// PreservePaths preserves original path structure in trash
 	PreservePaths bool
 
-	// UseCompression enables compression for trashed files
+	// UseCompression enables compression for files trashed in the legacy store
 	UseCompression bool
 
+	// Compression contains the format and the size threshold used when UseCompression is enabled
+	Compression config.CompressionConfig
+
+	// Checksum is the algorithm used to record a checksum of every trashed file
+	// (e.g., "sha256"). Checksums are not recorded if empty.
+	Checksum string
+
+	// Dedup stores identical file contents once in the legacy storage,
+	// hardlinking every trashed copy to a content-addressed blob
+	Dedup bool
+
+	// ChecksumMismatch is what Restore does with a file not matching its checksum
+	ChecksumMismatch MismatchPolicy
+
 	// History contains history-related configuration
 	History config.History
 
+	// Retention contains the limits used to expire old files in the trash
+	Retention config.RetentionConfig
+
 	// For legacy configuration
 	GomiDir string
-	RunID   string
+
+	// JournalDir is where files being moved in and out of trash are recorded,
+	// so that interrupted moves are recovered. Journaling is disabled if empty.
+	JournalDir string
+
+	// RunID identifies the current invocation and is recorded with every trashed file
+	RunID string
 }
 
 // NewDefaultConfig creates a new Config with default values
@@ -79,7 +107,12 @@ func (c *Config) Validate() error {
 		if c.Strategy == StrategyLegacy {
 			c.HomeTrashDir = filepath.Join(home, ".gomi")
 		} else {
-			c.HomeTrashDir = filepath.Join(home, ".local", "share", "Trash")
+			// Follow $XDG_DATA_HOME as the XDG storage does
+			dataDir := os.Getenv("XDG_DATA_HOME")
+			if dataDir == "" {
+				dataDir = filepath.Join(home, ".local", "share")
+			}
+			c.HomeTrashDir = filepath.Join(dataDir, "Trash")
 		}
 	}
 
@@ -0,0 +1,177 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ConflictPolicy is how a file is restored onto a path that already exists
+type ConflictPolicy string
+
+const (
+	// ConflictPrompt asks for a new name. The caller prompts, and restoring
+	// with this policy fails with ErrFileExists as there is nobody to ask.
+	ConflictPrompt ConflictPolicy = "prompt"
+
+	// ConflictRename restores the file next to the existing one with a numbered suffix
+	ConflictRename ConflictPolicy = "rename"
+
+	// ConflictOverwrite moves the existing file to trash before restoring
+	ConflictOverwrite ConflictPolicy = "overwrite"
+
+	// ConflictSkip leaves the file in trash
+	ConflictSkip ConflictPolicy = "skip"
+
+	// ConflictMerge restores the entries of a directory into the existing directory.
+	// Entries clashing with existing files are restored with a numbered suffix.
+	ConflictMerge ConflictPolicy = "merge"
+)
+
+// ErrSkipped is returned when a file is left in trash by ConflictSkip
+var ErrSkipped = errors.New("skipped as the destination already exists")
+
+// ParseConflictPolicy returns the policy named s, which defaults to ConflictPrompt
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case "":
+		return ConflictPrompt, nil
+	case ConflictPrompt, ConflictRename, ConflictOverwrite, ConflictSkip, ConflictMerge:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy: %q", s)
+	}
+}
+
+// RestoreOnConflict restores the file to dst like Restore, resolving a conflict
+// with an existing file by the policy. It returns where the file was restored.
+func (m *Manager) RestoreOnConflict(file *File, dst string, policy ConflictPolicy) (string, error) {
+	if dst == "" {
+		dst = file.OriginalPath
+	}
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		return dst, m.Restore(file, dst)
+	}
+
+	switch policy {
+	case ConflictSkip:
+		return "", ErrSkipped
+	case ConflictRename, ConflictOverwrite, ConflictMerge:
+	default:
+		return "", fmt.Errorf("%w: %s", ErrFileExists, dst)
+	}
+
+	// The file is verified before anything is changed, as overwriting
+	// moves the existing file to trash first
+	if err := m.config.VerifyBeforeRestore(file); err != nil {
+		return "", err
+	}
+	switch policy {
+	case ConflictRename:
+		dst = UniquePath(dst)
+		return dst, m.restore(file, dst)
+	case ConflictOverwrite:
+		return dst, m.restoreOverwrite(file, dst)
+	default:
+		return dst, m.restoreMerge(file, dst)
+	}
+}
+
+// restoreOverwrite restores the file onto dst, moving the existing file to trash.
+// The file is restored next to dst first, so that the existing file is left
+// in place if restoring fails, and is then renamed over it.
+func (m *Manager) restoreOverwrite(file *File, dst string) error {
+	tmp := tempSibling(dst, "overwrite")
+	if err := m.restore(file, tmp); err != nil {
+		return err
+	}
+	if err := m.Put(dst); err != nil {
+		return fmt.Errorf("failed to move %s to trash, the restored file is left in %s: %w", dst, tmp, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("failed to restore to %s, the restored file is left in %s: %w", dst, tmp, err)
+	}
+	return nil
+}
+
+// restoreMerge restores a directory into the existing directory at dst.
+// The directory is restored next to dst first, so that it is decompressed if needed
+// and its entries are then moved into dst by renaming.
+func (m *Manager) restoreMerge(file *File, dst string) error {
+	fi, err := os.Lstat(dst)
+	if err != nil {
+		return err
+	}
+	if !file.IsDir || !fi.IsDir() {
+		return fmt.Errorf("%w: %s (only directories can be merged)", ErrFileExists, dst)
+	}
+
+	tmp := tempSibling(dst, "merge")
+	if err := m.restore(file, tmp); err != nil {
+		return err
+	}
+	if err := mergeDir(tmp, dst); err != nil {
+		return fmt.Errorf("failed to merge into %s, what is left is in %s: %w", dst, tmp, err)
+	}
+	return nil
+}
+
+// mergeDir moves the entries of src into dst, merging directories recursively,
+// and removes src once it is empty
+func mergeDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		from := filepath.Join(src, entry.Name())
+		to := filepath.Join(dst, entry.Name())
+
+		fi, err := os.Lstat(to)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case entry.IsDir() && fi.IsDir():
+			if err := mergeDir(from, to); err != nil {
+				return err
+			}
+			continue
+		default:
+			to = UniquePath(to)
+			slog.Debug("merge conflict, keep both", "path", to)
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+	}
+	return os.Remove(src)
+}
+
+// tempSibling returns a hidden path next to path to restore a file to before moving it there
+func tempSibling(path, suffix string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%s.%s", filepath.Base(path), uuid.New().String(), suffix))
+}
+
+// UniquePath returns a path that does not exist by adding a numbered suffix
+// to the name of path, before its extension: "a.txt" becomes "a_1.txt", "a_2.txt" and so on
+func UniquePath(path string) string {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	if ext == name {
+		// Dotfiles like ".bashrc" have no extension
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s_%d%s", stem, n, ext))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/config"
+)
+
+// dirStorage is a Storage moving files in and out of a directory
+type dirStorage struct {
+	Storage
+	root string
+}
+
+func (s *dirStorage) Info() *StorageInfo {
+	return &StorageInfo{Trashes: []string{s.root}}
+}
+
+func (s *dirStorage) Put(src string) error {
+	return os.Rename(src, filepath.Join(s.root, filepath.Base(src)))
+}
+
+func (s *dirStorage) Restore(file *File, dst string) error {
+	return os.Rename(file.TrashPath, dst)
+}
+
+func (s *dirStorage) List() ([]*File, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for _, entry := range entries {
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		file := &File{Name: entry.Name(), TrashPath: filepath.Join(s.root, entry.Name()), DeletedAt: fi.ModTime()}
+		file.SetStorage(s)
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (s *dirStorage) Remove(file *File) error {
+	return os.RemoveAll(file.TrashPath)
+}
+
+func TestRestoreOnConflict(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for restore conflicts")
+	}
+
+	tests := []struct {
+		policy  ConflictPolicy
+		wantDst string
+		wantErr error
+		// want maps files under the original directory to their content
+		want map[string]string
+		// trashed lists the names left in the trash
+		trashed []string
+	}{
+		{
+			policy:  ConflictPrompt,
+			wantErr: ErrFileExists,
+			want:    map[string]string{"d/a": "old", "d/b": "old"},
+			trashed: []string{"trashed"},
+		},
+		{
+			policy:  ConflictSkip,
+			wantErr: ErrSkipped,
+			want:    map[string]string{"d/a": "old", "d/b": "old"},
+			trashed: []string{"trashed"},
+		},
+		{
+			policy:  ConflictRename,
+			wantDst: "d_1",
+			want:    map[string]string{"d/a": "old", "d/b": "old", "d_1/a": "new", "d_1/c/e": "new"},
+		},
+		{
+			policy:  ConflictOverwrite,
+			wantDst: "d",
+			want:    map[string]string{"d/a": "new", "d/c/e": "new"},
+			trashed: []string{"d"},
+		},
+		{
+			policy:  ConflictMerge,
+			wantDst: "d",
+			want:    map[string]string{"d/a": "old", "d/a_1": "new", "d/b": "old", "d/c/e": "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			home := t.TempDir()
+			trashDir := t.TempDir()
+			write := func(path, content string) {
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write(filepath.Join(home, "d", "a"), "old")
+			write(filepath.Join(home, "d", "b"), "old")
+			write(filepath.Join(trashDir, "trashed", "a"), "new")
+			write(filepath.Join(trashDir, "trashed", "c", "e"), "new")
+
+			m := &Manager{storages: []Storage{&dirStorage{root: trashDir}}}
+			file := &File{
+				Name:         "d",
+				OriginalPath: filepath.Join(home, "d"),
+				TrashPath:    filepath.Join(trashDir, "trashed"),
+				IsDir:        true,
+			}
+
+			dst, err := m.RestoreOnConflict(file, "", tt.policy)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RestoreOnConflict() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if want := filepath.Join(home, tt.wantDst); dst != want {
+				t.Errorf("restored to %q, want %q", dst, want)
+			}
+
+			got := map[string]string{}
+			err = filepath.WalkDir(home, func(path string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				data, err := os.ReadFile(path)
+				rel, _ := filepath.Rel(home, path)
+				got[filepath.ToSlash(rel)] = string(data)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+			for path, content := range tt.want {
+				if got[path] != content {
+					t.Errorf("%s = %q, want %q", path, got[path], content)
+				}
+			}
+
+			for _, name := range tt.trashed {
+				if _, err := os.Lstat(filepath.Join(trashDir, name)); err != nil {
+					t.Errorf("%s is not in the trash", name)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreOverwrite(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for restore conflicts")
+	}
+
+	tests := []struct {
+		name    string
+		missing bool
+		// wantDst and wantTrash are the contents of the original path and the trash
+		wantDst   string
+		wantTrash []string
+	}{
+		{name: "restored", wantDst: "old", wantTrash: []string{"new"}},
+		{name: "restore fails", missing: true, wantDst: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			trashDir := t.TempDir()
+			dst := filepath.Join(home, "a.txt")
+			if err := os.WriteFile(dst, []byte("new"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			file := &File{Name: "a.txt", OriginalPath: dst, TrashPath: filepath.Join(trashDir, "trashed")}
+			if !tt.missing {
+				if err := os.WriteFile(file.TrashPath, []byte("old"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				old := time.Now().Add(-time.Hour)
+				if err := os.Chtimes(file.TrashPath, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The file being restored is the oldest one, which retention would evict first
+			m := &Manager{
+				storages:  []Storage{&dirStorage{root: trashDir}},
+				config:    Config{Retention: config.RetentionConfig{MaxItems: 1}},
+				startedAt: time.Now().Add(time.Hour),
+			}
+			_, err := m.RestoreOnConflict(file, "", ConflictOverwrite)
+			if tt.missing != (err != nil) {
+				t.Fatalf("RestoreOnConflict() error = %v", err)
+			}
+
+			if data, err := os.ReadFile(dst); err != nil || string(data) != tt.wantDst {
+				t.Errorf("%s = %q (%v), want %q", dst, data, err, tt.wantDst)
+			}
+			entries, err := os.ReadDir(trashDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var trashed []string
+			for _, entry := range entries {
+				data, err := os.ReadFile(filepath.Join(trashDir, entry.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				trashed = append(trashed, string(data))
+			}
+			if len(trashed) != len(tt.wantTrash) || len(trashed) > 0 && trashed[0] != tt.wantTrash[0] {
+				t.Errorf("trash = %q, want %q", trashed, tt.wantTrash)
+			}
+			if leftovers, _ := filepath.Glob(filepath.Join(home, ".*")); len(leftovers) > 0 {
+				t.Errorf("left %v", leftovers)
+			}
+		})
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "a_1.txt", ".bashrc", "dir"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "a_2.txt"},
+		{".bashrc", ".bashrc_1"},
+		{"dir", "dir_1"},
+		{"new", "new_1"},
+	}
+	for _, tt := range tests {
+		if got := UniquePath(filepath.Join(dir, tt.name)); got != filepath.Join(dir, tt.want) {
+			t.Errorf("UniquePath(%q) = %q, want %q", tt.name, filepath.Base(got), tt.want)
+		}
+	}
+}
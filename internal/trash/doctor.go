@@ -0,0 +1,121 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ProblemKind is a kind of inconsistency found in a storage
+type ProblemKind string
+
+const (
+	// ProblemOrphanFile is a file in the trash without metadata
+	// (no .trashinfo, or no entry in the legacy history)
+	ProblemOrphanFile ProblemKind = "orphan file"
+
+	// ProblemOrphanInfo is metadata of a file that is missing from the trash
+	ProblemOrphanInfo ProblemKind = "orphan info"
+
+	// ProblemInvalidInfo is a .trashinfo file that cannot be parsed
+	ProblemInvalidInfo ProblemKind = "invalid info"
+
+	// ProblemPermission is a trash directory with unsafe permissions
+	ProblemPermission ProblemKind = "bad permissions"
+
+	// ProblemStaleFile is a leftover temporary or backup file
+	ProblemStaleFile ProblemKind = "stale file"
+
+	// ProblemDuplicate is a file recorded more than once
+	ProblemDuplicate ProblemKind = "duplicate"
+
+	// ProblemLayout is a file stored in another layout than the configured one
+	ProblemLayout ProblemKind = "other layout"
+)
+
+// OrphanAction is how an orphan file is fixed
+type OrphanAction string
+
+const (
+	// OrphanAdopt keeps the file in the trash with synthesized metadata.
+	// As its original location is unknown, it is restored to the home directory
+	// (or to the top directory of its mount point for an external trash).
+	OrphanAdopt OrphanAction = "adopt"
+
+	// OrphanDelete permanently deletes the file
+	OrphanDelete OrphanAction = "delete"
+)
+
+// Problem is an inconsistency found in a storage
+type Problem struct {
+	Kind ProblemKind
+
+	// Path is the file in the trash the problem is about
+	Path string
+
+	// Detail describes the problem
+	Detail string
+
+	// fix repairs the problem, and is nil if it can only be reported
+	fix func(OrphanAction) error
+}
+
+// NewProblem returns a problem that is repaired by fix, which can be nil
+func NewProblem(kind ProblemKind, path, detail string, fix func(OrphanAction) error) Problem {
+	return Problem{Kind: kind, Path: path, Detail: detail, fix: fix}
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s (%s)", p.Kind, p.Path, p.Detail)
+}
+
+// Fixable returns true if the problem can be repaired
+func (p Problem) Fixable() bool {
+	return p.fix != nil
+}
+
+// Fix repairs the problem. The action only applies to orphan files.
+func (p Problem) Fix(action OrphanAction) error {
+	if p.fix == nil {
+		return errors.New("cannot be fixed automatically")
+	}
+	return p.fix(action)
+}
+
+// Checker is implemented by storages that can find inconsistencies in themselves
+type Checker interface {
+	// Check scans the storage and returns the problems found
+	Check() ([]Problem, error)
+}
+
+// Check scans every storage that implements Checker
+func (m *Manager) Check() ([]Problem, error) {
+	var (
+		problems []Problem
+		errs     []error
+	)
+	for _, storage := range m.storages {
+		checker, ok := storage.(Checker)
+		if !ok {
+			continue
+		}
+		found, err := checker.Check()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check %s storage: %w", storage.Info().Type, err))
+			continue
+		}
+		problems = append(problems, found...)
+	}
+	return problems, errors.Join(errs...)
+}
+
+// tempName matches the temporary files gomi writes next to their destination,
+// named ".<name>.<uuid>.tmp"
+var tempName = regexp.MustCompile(`^\..+\.[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.tmp$`)
+
+// IsTempName reports whether name is the name of a temporary file written by gomi.
+// A trashed file can have such a name too, so it is only a leftover if no metadata
+// refers to it.
+func IsTempName(name string) bool {
+	return tempName.MatchString(name)
+}
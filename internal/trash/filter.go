@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/babarot/gomi/internal/config"
-	"github.com/babarot/gomi/internal/utils/fs"
 	"github.com/docker/go-units"
 	"github.com/gobwas/glob"
 	"github.com/k1LoW/duration"
@@ -21,6 +20,8 @@ type Filterable interface {
 	GetPath() string
 	// GetDeletedAt returns when the file was trashed
 	GetDeletedAt() time.Time
+	// GetSize returns the size the file takes in trash
+	GetSize() (int64, error)
 }
 
 // FilterOptions holds filtering configuration
@@ -134,7 +135,7 @@ func rejectByGlobs[T Filterable](items []T, globs []string) []T {
 func rejectBySize[T Filterable](items []T, size config.SizeConfig) []T {
 	var filtered []T
 	for _, item := range items {
-		dirSize, err := fs.DirSize(item.GetPath())
+		dirSize, err := item.GetSize()
 		if err != nil {
 			continue // Skip items we can't size
 		}
@@ -0,0 +1,363 @@
+// Package journal records the files being moved in and out of the trash,
+// so that moves interrupted by a crash or a kill can be completed or rolled
+// back the next time gomi starts.
+//
+// An entry is written before a file is moved (its intent), updated once a
+// complete copy of the file is at its destination, and removed once the move
+// and the metadata of the file are committed. Each entry is locked by the
+// process writing it, so entries of running processes are never recovered.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/google/uuid"
+)
+
+// Op is the operation moving a file
+type Op string
+
+const (
+	// OpPut moves a file to the trash
+	OpPut Op = "put"
+
+	// OpRestore moves a file out of the trash
+	OpRestore Op = "restore"
+
+	// OpMove moves a file within the trash, such as to another layout
+	OpMove Op = "move"
+)
+
+// Step is how far an operation went
+type Step string
+
+const (
+	// StepBegin is recorded before the file is moved
+	StepBegin Step = "begin"
+
+	// StepCopied is recorded once a complete copy of the file is at Dst,
+	// and before Src is removed
+	StepCopied Step = "copied"
+)
+
+const (
+	entryExt = ".json"
+	lockExt  = ".lock"
+)
+
+// Entry is an operation moving a file from Src to Dst
+type Entry struct {
+	ID      string `json:"id"`
+	Op      Op     `json:"op"`
+	Storage string `json:"storage"`
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+
+	// DstExisted tells that Dst existed before the move,
+	// so it is never removed when the move is rolled back
+	DstExisted bool `json:"dst_existed,omitempty"`
+
+	// Target is where a restored file is renamed to once it is complete at Dst,
+	// a hidden sibling of Target (see StagingPath), so that recovering a restore
+	// never removes anything at the path of the user
+	Target string `json:"target,omitempty"`
+
+	// Info is the .trashinfo of the file in the XDG storage
+	Info string `json:"info,omitempty"`
+
+	// Record is the history entry of the file in the legacy storage
+	Record json.RawMessage `json:"record,omitempty"`
+
+	Step      Step      `json:"step"`
+	StartedAt time.Time `json:"started_at"`
+
+	// unlock releases the lock of the entry held while it is pending
+	unlock func()
+}
+
+// Journal is a directory of pending entries
+type Journal struct {
+	dir string
+}
+
+// Open opens the journal in dir, creating it if needed.
+// It returns nil if dir is empty, and every method of a nil journal does nothing.
+func Open(dir string) (*Journal, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	return &Journal{dir: dir}, nil
+}
+
+// Begin records the intent of the entry before its file is moved
+func (j *Journal) Begin(e *Entry) error {
+	if j == nil {
+		return nil
+	}
+	e.ID = uuid.New().String()
+	e.Step = StepBegin
+	e.StartedAt = time.Now()
+	if _, err := os.Lstat(e.Dst); err == nil {
+		e.DstExisted = true
+	}
+
+	// The entry is locked before it is written, so that it is never taken
+	// for an interrupted one by another process
+	unlock, err := fs.Lock(j.path(e.ID, lockExt))
+	if err != nil {
+		return fmt.Errorf("failed to lock journal entry: %w", err)
+	}
+	e.unlock = unlock
+	if err := j.write(e); err != nil {
+		j.release(e)
+		return err
+	}
+	return nil
+}
+
+// Checkpoint records that the entry has reached the step
+func (j *Journal) Checkpoint(e *Entry, step Step) error {
+	if j == nil {
+		return nil
+	}
+	e.Step = step
+	return j.write(e)
+}
+
+// Commit removes the entry once its file and metadata are where they belong,
+// or once the operation has failed and been cleaned up
+func (j *Journal) Commit(e *Entry) {
+	if j == nil || e.ID == "" {
+		return
+	}
+	if err := os.Remove(j.path(e.ID, entryExt)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to remove journal entry", "id", e.ID, "error", err)
+	}
+	j.release(e)
+}
+
+// Release unlocks a pending entry without committing it, so that it is recovered again later
+func (j *Journal) Release(e *Entry) {
+	if j == nil {
+		return
+	}
+	j.release(e)
+}
+
+// Pending returns the entries of the storage left by processes that are no longer running.
+// Each of them is locked until it is committed.
+func (j *Journal) Pending(storage string) ([]*Entry, error) {
+	if j == nil {
+		return nil, nil
+	}
+	dirEntries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var entries []*Entry
+	for _, d := range dirEntries {
+		id, ok := strings.CutSuffix(d.Name(), entryExt)
+		if !ok || strings.HasPrefix(id, ".") {
+			continue
+		}
+		unlock, ok, err := fs.TryLock(j.path(id, lockExt))
+		if err != nil {
+			slog.Warn("failed to lock journal entry", "id", id, "error", err)
+			continue
+		}
+		if !ok {
+			// The process writing the entry is still running
+			continue
+		}
+
+		e, err := j.read(id)
+		if err != nil {
+			unlock()
+			if os.IsNotExist(err) {
+				// Committed meanwhile
+				os.Remove(j.path(id, lockExt))
+				continue
+			}
+			slog.Warn("failed to read journal entry", "id", id, "error", err)
+			continue
+		}
+		if e.Storage != storage {
+			unlock()
+			continue
+		}
+		e.unlock = unlock
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Paths returns the paths that entries of the storage are moving, including the
+// entries of running processes, so that they are not taken for inconsistencies
+func (j *Journal) Paths(storage string) (map[string]bool, error) {
+	paths := make(map[string]bool)
+	if j == nil {
+		return paths, nil
+	}
+	dirEntries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	for _, d := range dirEntries {
+		id, ok := strings.CutSuffix(d.Name(), entryExt)
+		if !ok || strings.HasPrefix(id, ".") {
+			continue
+		}
+		e, err := j.read(id)
+		if err != nil || e.Storage != storage {
+			// Committed meanwhile, or being written
+			continue
+		}
+		for _, path := range []string{e.Src, e.Dst, e.Target, e.Info} {
+			if path != "" {
+				paths[path] = true
+			}
+		}
+	}
+	return paths, nil
+}
+
+// StagingPath returns the hidden path next to dst where a file is restored
+// before it is renamed to dst
+func StagingPath(dst string) string {
+	return filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.%s.restore", filepath.Base(dst), uuid.New().String()))
+}
+
+// Resolve completes or rolls back the move of an interrupted entry,
+// and reports whether the file ended up at Dst, or at Target if the entry has one.
+// The caller then records or discards the metadata of the file accordingly,
+// and commits the entry. Only Src, which is in the trash for a restore, and Dst,
+// which is in the trash or a staging path, are ever removed.
+func (e *Entry) Resolve() (bool, error) {
+	if e.Step == StepCopied {
+		// Dst is complete, so what is left of Src is removed
+		if err := os.RemoveAll(e.Src); err != nil {
+			return false, fmt.Errorf("failed to remove %s: %w", e.Src, err)
+		}
+		return e.finish()
+	}
+
+	_, srcErr := os.Lstat(e.Src)
+	_, dstErr := os.Lstat(e.Dst)
+	switch {
+	case os.IsNotExist(srcErr) && dstErr == nil:
+		// Src was renamed to Dst, which is atomic
+		return e.finish()
+	case srcErr == nil:
+		if dstErr != nil || e.DstExisted {
+			return false, nil
+		}
+		if e.Op == OpRestore && e.Target == "" {
+			// Entries of older versions restored straight to the path of the user
+			slog.Warn("interrupted restore left a partial copy", "path", e.Dst)
+			return false, nil
+		}
+		// Src is intact, and a partial copy at Dst is discarded
+		if err := os.RemoveAll(e.Dst); err != nil {
+			return false, fmt.Errorf("failed to remove %s: %w", e.Dst, err)
+		}
+		return false, nil
+	case os.IsNotExist(srcErr) && os.IsNotExist(dstErr):
+		if e.Target != "" {
+			if _, err := os.Lstat(e.Target); err == nil {
+				// Dst was renamed to Target, but the entry was not committed
+				return true, nil
+			}
+		}
+		// Nothing is left to move, so the metadata is discarded
+		slog.Warn("interrupted move left no file", "src", e.Src, "dst", e.Dst)
+		return false, nil
+	default:
+		return false, errors.Join(srcErr, dstErr)
+	}
+}
+
+// finish renames the complete file at Dst to Target, if the entry has one
+func (e *Entry) finish() (bool, error) {
+	if e.Target == "" {
+		return true, nil
+	}
+	if _, err := os.Lstat(e.Target); err == nil {
+		return false, fmt.Errorf("%s exists, the restored file is left in %s", e.Target, e.Dst)
+	}
+	if err := os.Rename(e.Dst, e.Target); err != nil {
+		return false, fmt.Errorf("failed to rename %s to %s: %w", e.Dst, e.Target, err)
+	}
+	return true, nil
+}
+
+// String returns a description of the entry for messages
+func (e *Entry) String() string {
+	if e.Target != "" {
+		return fmt.Sprintf("%s %s -> %s", e.Op, e.Src, e.Target)
+	}
+	return fmt.Sprintf("%s %s -> %s", e.Op, e.Src, e.Dst)
+}
+
+func (j *Journal) path(id, ext string) string {
+	return filepath.Join(j.dir, id+ext)
+}
+
+// write saves the entry atomically, so that a partial entry is never read
+func (j *Journal) write(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmpPath := j.path("."+e.ID, entryExt)
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err := errors.Join(err, f.Close()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path(e.ID, entryExt)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+func (j *Journal) read(id string) (*Entry, error) {
+	data, err := os.ReadFile(j.path(id, entryExt))
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// release unlocks the entry and removes its lock file
+func (j *Journal) release(e *Entry) {
+	if e.unlock == nil {
+		return
+	}
+	e.unlock()
+	e.unlock = nil
+	os.Remove(j.path(e.ID, lockExt))
+}
@@ -0,0 +1,137 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		op         Op
+		step       Step
+		src        bool
+		dst        bool
+		dstExisted bool
+		withTarget bool // the entry restores to a target through Dst
+		target     bool // something exists at the target
+		want       bool
+		wantErr    bool
+		wantSrc    bool
+		wantDst    bool
+		wantTarget bool
+	}{
+		{name: "renamed", step: StepBegin, src: false, dst: true, want: true, wantDst: true},
+		{name: "partial copy", step: StepBegin, src: true, dst: true, want: false, wantSrc: true},
+		{name: "not started", step: StepBegin, src: true, want: false, wantSrc: true},
+		{name: "existing dst", step: StepBegin, src: true, dst: true, dstExisted: true, want: false, wantSrc: true, wantDst: true},
+		{name: "copied", step: StepCopied, src: true, dst: true, want: true, wantDst: true},
+		{name: "copied and removed", step: StepCopied, dst: true, want: true, wantDst: true},
+		{name: "lost", step: StepBegin, want: false},
+
+		{name: "restore renamed to staging", op: OpRestore, step: StepBegin, dst: true, withTarget: true, want: true, wantTarget: true},
+		{name: "restore copied to staging", op: OpRestore, step: StepCopied, src: true, dst: true, withTarget: true, want: true, wantTarget: true},
+		{name: "restore partial copy", op: OpRestore, step: StepBegin, src: true, dst: true, withTarget: true, want: false, wantSrc: true},
+		{name: "restore renamed to target", op: OpRestore, step: StepBegin, withTarget: true, target: true, want: true, wantTarget: true},
+		{name: "restore not started, target created", op: OpRestore, step: StepBegin, src: true, withTarget: true, target: true, want: false, wantSrc: true, wantTarget: true},
+		{name: "restore copied, target created", op: OpRestore, step: StepCopied, src: true, dst: true, withTarget: true, target: true, wantErr: true, wantDst: true, wantTarget: true},
+		{name: "restore of older versions", op: OpRestore, step: StepBegin, src: true, dst: true, want: false, wantSrc: true, wantDst: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			e := &Entry{
+				Op:         tt.op,
+				Src:        filepath.Join(dir, "src"),
+				Dst:        filepath.Join(dir, "dst"),
+				DstExisted: tt.dstExisted,
+				Step:       tt.step,
+			}
+			if tt.withTarget {
+				e.Target = filepath.Join(dir, "target")
+			}
+			if tt.src {
+				if err := os.MkdirAll(filepath.Join(e.Src, "a"), 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.dst {
+				if err := os.MkdirAll(e.Dst, 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.target {
+				if err := os.MkdirAll(filepath.Join(e.Target, "mine"), 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := e.Resolve()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+			if _, err := os.Lstat(e.Src); (err == nil) != tt.wantSrc {
+				t.Errorf("src exists = %v, want %v", err == nil, tt.wantSrc)
+			}
+			if _, err := os.Lstat(e.Dst); (err == nil) != tt.wantDst {
+				t.Errorf("dst exists = %v, want %v", err == nil, tt.wantDst)
+			}
+			if e.Target != "" {
+				if _, err := os.Lstat(e.Target); (err == nil) != tt.wantTarget {
+					t.Errorf("target exists = %v, want %v", err == nil, tt.wantTarget)
+				}
+			}
+			if tt.target {
+				if _, err := os.Lstat(filepath.Join(e.Target, "mine")); err != nil {
+					t.Errorf("what the user created at the target is removed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	j, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry of a running process is not pending
+	running := &Entry{Op: OpPut, Storage: "xdg", Src: "/a", Dst: "/b"}
+	if err := j.Begin(running); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := j.Pending("xdg"); err != nil || len(entries) != 0 {
+		t.Fatalf("Pending() = %v, %v, want no entries", entries, err)
+	}
+
+	// The process is gone, leaving the entry behind
+	if err := j.Checkpoint(running, StepCopied); err != nil {
+		t.Fatal(err)
+	}
+	running.unlock()
+
+	if entries, err := j.Pending("legacy"); err != nil || len(entries) != 0 {
+		t.Fatalf("Pending() = %v, %v, want no entries of another storage", entries, err)
+	}
+	entries, err := j.Pending("xdg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != running.ID || entries[0].Step != StepCopied {
+		t.Fatalf("Pending() = %v, want %v", entries, running)
+	}
+
+	j.Commit(entries[0])
+	if entries, err := j.Pending("xdg"); err != nil || len(entries) != 0 {
+		t.Errorf("Pending() = %v, %v after commit, want no entries", entries, err)
+	}
+	if files, _ := os.ReadDir(j.dir); len(files) != 0 {
+		t.Errorf("files left in journal: %v", files)
+	}
+}
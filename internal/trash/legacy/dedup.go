@@ -0,0 +1,361 @@
+package legacy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/checksum"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/google/uuid"
+)
+
+// blobsDirname is the directory of the content-addressed blobs when Dedup is enabled,
+// e.g. ~/.gomi/blobs/ab/abcdef...
+//
+// Each trashed file with the content of a blob is a hardlink to it, so identical
+// contents take space only once. The entries of history referencing a blob are its
+// reference count, and a blob is removed once no entry references it anymore.
+const blobsDirname = "blobs"
+
+// blobsRoot returns the root of the blob store
+func (s *Storage) blobsRoot() string {
+	return filepath.Join(s.root, blobsDirname)
+}
+
+// blobPath returns the path of the blob with the digest
+func (s *Storage) blobPath(digest string) string {
+	// Digests are "sha256:<hex>", and blobs are spread by the first byte
+	_, hex, _ := strings.Cut(digest, ":")
+	return filepath.Join(s.blobsRoot(), hex[:2], hex)
+}
+
+// scanBlobs returns the files of the file or directory at abs that can be deduplicated,
+// with their metadata. Empty files, files with other hardlinks and files in directories
+// that are not writable are left out, as they would not be restored as they were.
+// The files are hashed by linkBlobs once in the trash, as they may change until then.
+func (s *Storage) scanBlobs(abs string) []history.Blob {
+	if !s.config.Dedup {
+		return nil
+	}
+
+	var blobs []history.Blob
+	err := filepath.WalkDir(abs, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if fi.Mode().Perm()&0200 == 0 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || fi.Size() == 0 || fs.LinkCount(fi) > 1 {
+			return nil
+		}
+
+		// The metadata is read before the file is hashed, which changes its access time
+		metadata, err := fs.ReadMetadata(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(abs, path)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, history.Blob{
+			Path:     rel,
+			Metadata: metadata,
+		})
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to scan files to deduplicate", "path", abs, "error", err)
+		return nil
+	}
+	return blobs
+}
+
+// linkBlobs replaces the files of the entry moved to trashPath with hardlinks to
+// the blobs of the same content, adding the blobs that do not exist yet.
+// It returns the blobs linked with their digests, leaving out the files that could
+// not be deduplicated. The history lock must be held.
+func (s *Storage) linkBlobs(trashPath string, blobs []history.Blob) []history.Blob {
+	var linked []history.Blob
+	for _, b := range blobs {
+		path := filepath.Join(trashPath, b.Path)
+		digest, size, err := s.linkBlob(path)
+		if err != nil {
+			slog.Warn("failed to deduplicate file", "path", path, "error", err)
+			continue
+		}
+		b.Digest, b.Size = digest, size
+		linked = append(linked, b)
+	}
+	return linked
+}
+
+// linkBlob hashes the file at path in the trash and links it to its blob.
+// It returns the digest and size of the content.
+func (s *Storage) linkBlob(path string) (string, int64, error) {
+	// The file is checked again, as it may have changed since it was scanned
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	if !fi.Mode().IsRegular() || fi.Size() == 0 || fs.LinkCount(fi) > 1 {
+		return "", 0, fmt.Errorf("%s changed since it was scanned", path)
+	}
+	digest, err := checksum.Sum(path, checksum.SHA256)
+	if err != nil {
+		return "", 0, err
+	}
+
+	blob := s.blobPath(digest)
+	if _, err := os.Lstat(blob); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0700); err != nil {
+			return "", 0, err
+		}
+		return digest, fi.Size(), os.Link(path, blob)
+	} else if err != nil {
+		return "", 0, err
+	}
+
+	// The file is compared to the blob right before replacing it,
+	// so that a file written after it was hashed is never lost
+	same, err := sameContent(path, blob)
+	if err != nil {
+		return "", 0, err
+	}
+	if !same {
+		return "", 0, fmt.Errorf("%s differs from the blob %s", path, digest)
+	}
+
+	// The blob is linked next to the file first, so that the file is replaced atomically
+	tmp := tempPath(path)
+	if err := os.Link(blob, tmp); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", 0, err
+	}
+	return digest, fi.Size(), nil
+}
+
+// sameContent reports whether the files at a and b have the same bytes
+func sameContent(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufa := make([]byte, 32*1024)
+	bufb := make([]byte, 32*1024)
+	for {
+		na, erra := io.ReadFull(fa, bufa)
+		nb, errb := io.ReadFull(fb, bufb)
+		if !bytes.Equal(bufa[:na], bufb[:nb]) {
+			return false, nil
+		}
+		if erra == io.EOF || erra == io.ErrUnexpectedEOF {
+			return errb == io.EOF || errb == io.ErrUnexpectedEOF, nil
+		}
+		if erra != nil {
+			return false, erra
+		}
+		if errb != nil {
+			return false, errb
+		}
+	}
+}
+
+// unlinkBlobs gives the files of the entry sharing their inode with a blob a copy of
+// their own, with the metadata they had when trashed, so that the files can be moved
+// out of the trash without changing the blob. The history lock must be held.
+func (s *Storage) unlinkBlobs(trashPath string, blobs []history.Blob) error {
+	for _, b := range blobs {
+		path := filepath.Join(trashPath, b.Path)
+		if !sameFile(path, s.blobPath(b.Digest)) {
+			continue
+		}
+		if err := unlinkBlob(path, b.Metadata); err != nil {
+			return fmt.Errorf("failed to copy %s out of the blob store: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func unlinkBlob(path string, metadata *fs.Metadata) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := tempPath(path)
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if metadata != nil {
+		if err := metadata.Apply(tmp); err != nil {
+			slog.Warn("failed to keep metadata of deduplicated file", "path", path, "error", err)
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// collectBlobs removes the blobs that are no longer referenced by any entry in history.
+// The history lock must be held, and the history saved without the entries dropping them.
+func (s *Storage) collectBlobs(blobs []history.Blob) {
+	if len(blobs) == 0 {
+		return
+	}
+	refs := s.blobRefs()
+	for _, b := range blobs {
+		if refs[b.Digest] > 0 {
+			continue
+		}
+		if err := s.deleteBlob(b.Digest); err != nil {
+			slog.Warn("failed to remove unreferenced blob", "digest", b.Digest, "error", err)
+		}
+	}
+}
+
+func (s *Storage) deleteBlob(digest string) error {
+	blob := s.blobPath(digest)
+	if err := os.Remove(blob); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Leave the store empty, not full of empty directories
+	os.Remove(filepath.Dir(blob))
+	slog.Debug("removed unreferenced blob", "digest", digest)
+	return nil
+}
+
+// blobRefs returns the number of references to each blob in history
+func (s *Storage) blobRefs() map[string]int {
+	refs := make(map[string]int)
+	for _, f := range s.history.Files {
+		for _, b := range f.Blobs {
+			refs[b.Digest]++
+		}
+	}
+	return refs
+}
+
+// DedupStats reports how much space the blob store saves
+func (s *Storage) DedupStats() (trash.DedupStats, error) {
+	var stats trash.DedupStats
+	refs := s.blobRefs()
+	seen := make(map[string]bool, len(refs))
+	for _, f := range s.history.Files {
+		for _, b := range f.Blobs {
+			if seen[b.Digest] {
+				continue
+			}
+			seen[b.Digest] = true
+			stats.Blobs++
+			stats.Refs += refs[b.Digest]
+			stats.Stored += b.Size
+			stats.Saved += int64(refs[b.Digest]-1) * b.Size
+		}
+	}
+	return stats, nil
+}
+
+// checkBlobs reports the blobs that no entry in history references,
+// which are left behind when gomi is killed while removing a file
+func (s *Storage) checkBlobs() []trash.Problem {
+	var problems []trash.Problem
+	refs := s.blobRefs()
+	_ = filepath.WalkDir(s.blobsRoot(), func(path string, d iofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		digest := string(checksum.SHA256) + ":" + d.Name()
+		if refs[digest] > 0 {
+			return nil
+		}
+		problems = append(problems, trash.NewProblem(trash.ProblemStaleFile, path,
+			"blob not referenced by any file in history",
+			func(trash.OrphanAction) error {
+				return s.removeBlob(digest)
+			}))
+		return nil
+	})
+	return problems
+}
+
+// removeBlob removes the blob if it is still unreferenced
+func (s *Storage) removeBlob(digest string) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A file pointing at the blob may have been trashed since the check
+	if s.blobRefs()[digest] > 0 {
+		return nil
+	}
+	return s.deleteBlob(digest)
+}
+
+// findEntry returns the entry of history stored at path, or nil if there is none
+func (s *Storage) findEntry(path string) *history.File {
+	for i := range s.history.Files {
+		if s.history.Files[i].To == path {
+			return &s.history.Files[i]
+		}
+	}
+	return nil
+}
+
+// tempPath returns a temporary path next to path, which "gomi doctor" reports if left over
+func tempPath(path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%s.tmp", filepath.Base(path), uuid.New().String()))
+}
+
+func sameFile(a, b string) bool {
+	fa, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Lstat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fa, fb)
+}
@@ -0,0 +1,165 @@
+package legacy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/checksum"
+)
+
+func TestDedup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for legacy dedup")
+	}
+
+	root := t.TempDir()
+	storage, err := NewStorage(trash.Config{GomiDir: root, Dedup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := storage.(*Storage)
+
+	src := t.TempDir()
+	content := []byte("vendored")
+	write := func(rel string, mode os.FileMode) string {
+		path := filepath.Join(src, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a", 0600)
+	b := write("b", 0755)
+	write("dir/c", 0644)
+	// Empty files are not deduplicated
+	if err := os.WriteFile(filepath.Join(src, "dir/empty"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{a, b, filepath.Join(src, "dir")} {
+		if err := s.Put(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := s.DedupStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(content))
+	want := trash.DedupStats{Blobs: 1, Refs: 3, Stored: size, Saved: 2 * size}
+	if stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+
+	files, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := s.blobPath(s.history.Files[0].Blobs[0].Digest)
+	for _, f := range files {
+		path := f.TrashPath
+		if f.IsDir {
+			path = filepath.Join(path, "c")
+		}
+		if !sameFile(path, blob) {
+			t.Errorf("%s is not linked to the blob", path)
+		}
+	}
+
+	// The restored file is a copy with its own mode, and the blob is kept for the others
+	if err := s.Restore(files[1], ""); err != nil {
+		t.Fatal(err)
+	}
+	if sameFile(b, blob) {
+		t.Errorf("%s is restored as a link to the blob", b)
+	}
+	if fi, err := os.Stat(b); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("got %v (%v), want mode 0755", fi.Mode(), err)
+	}
+	if got, err := os.ReadFile(b); err != nil || string(got) != string(content) {
+		t.Errorf("got %q (%v), want %q", got, err, content)
+	}
+	if _, err := os.Stat(blob); err != nil {
+		t.Errorf("blob is removed while referenced: %v", err)
+	}
+
+	// The blob is removed with the last file pointing at it
+	for _, f := range []*trash.File{files[0], files[2]} {
+		if err := s.Remove(f); err != nil {
+			t.Fatal(err)
+		}
+		if problems := s.checkBlobs(); len(problems) > 0 {
+			t.Errorf("got problems %v after removing %s", problems, f.Name)
+		}
+	}
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Errorf("blob is left after every file is removed: %v", err)
+	}
+}
+
+func TestDedupChangedFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for legacy dedup")
+	}
+
+	root := t.TempDir()
+	storage, err := NewStorage(trash.Config{GomiDir: root, Dedup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := storage.(*Storage)
+	dir := t.TempDir()
+
+	// A file written after it was scanned is linked to the blob of its new content
+	path := filepath.Join(dir, "a")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	blobs := s.scanBlobs(path)
+	if err := os.WriteFile(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	linked := s.linkBlobs(path, blobs)
+	if len(linked) != 1 {
+		t.Fatalf("got %d blobs linked, want 1", len(linked))
+	}
+	want, err := checksum.Sum(path, checksum.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked[0].Digest != want {
+		t.Errorf("got digest %s, want %s", linked[0].Digest, want)
+	}
+	if got, err := os.ReadFile(s.blobPath(want)); err != nil || string(got) != "new" {
+		t.Errorf("got blob %q (%v), want %q", got, err, "new")
+	}
+
+	// A file is never replaced by a blob of other bytes
+	other := filepath.Join(dir, "b")
+	if err := os.WriteFile(other, []byte("other"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := checksum.Sum(other, checksum.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := s.blobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(blob), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blob, []byte("wrong"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if linked := s.linkBlobs(other, s.scanBlobs(other)); len(linked) != 0 {
+		t.Errorf("got %v linked to a blob of other bytes", linked)
+	}
+	if got, err := os.ReadFile(other); err != nil || string(got) != "other" {
+		t.Errorf("got %q (%v), want %q", got, err, "other")
+	}
+}
@@ -0,0 +1,236 @@
+package legacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+	"github.com/google/uuid"
+)
+
+// versionSuffix matches the suffix given to older versions of a path in the tree layout
+var versionSuffix = regexp.MustCompile(`\.~\d+~(/|$)`)
+
+// Check reports history entries pointing at missing files, duplicate entries,
+// files in the trash that are not in the history, leftover temporary files,
+// blobs no file points at and files stored in the other layout
+func (s *Storage) Check() ([]trash.Problem, error) {
+	var problems []trash.Problem
+
+	seenIDs := make(map[string]bool, len(s.history.Files))
+	seenPaths := make(map[string]bool, len(s.history.Files))
+	for _, f := range s.history.Files {
+		if seenIDs[f.ID] || seenPaths[f.To] {
+			problems = append(problems, trash.NewProblem(trash.ProblemDuplicate, f.To,
+				fmt.Sprintf("recorded more than once in history (id %s)", f.ID),
+				func(trash.OrphanAction) error {
+					return s.dropHistoryEntry(f)
+				}))
+			continue
+		}
+		seenIDs[f.ID] = true
+		seenPaths[f.To] = true
+
+		if _, err := os.Lstat(f.To); os.IsNotExist(err) {
+			problems = append(problems, trash.NewProblem(trash.ProblemOrphanInfo, f.To,
+				"recorded in history but missing from the trash",
+				func(trash.OrphanAction) error {
+					return s.dropHistoryEntry(f)
+				}))
+		}
+	}
+
+	// Directories containing trashed files are walked to find the ones not in history
+	parents := make(map[string]bool)
+	for path := range seenPaths {
+		for dir := filepath.Dir(path); strings.HasPrefix(dir, s.root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+
+	historyPath := filepath.Join(s.root, history.Filename)
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == s.root || path == historyPath || path == historyPath+lockSuffix || seenPaths[path] {
+			if d.IsDir() && path != s.root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		name := d.Name()
+		switch {
+		case path == s.blobsRoot():
+			problems = append(problems, s.checkBlobs()...)
+			return filepath.SkipDir
+		case path == historyPath+".backup":
+			if detail := s.checkBackup(path); detail != "" {
+				problems = append(problems, trash.NewProblem(trash.ProblemStaleFile, path, detail,
+					func(trash.OrphanAction) error {
+						return os.Remove(path)
+					}))
+			}
+			return nil
+		case parents[path]:
+			// Directories of the tree layout may look like temporary files
+			return nil
+		case trash.IsTempName(name) ||
+			filepath.Dir(path) == s.root && strings.HasPrefix(name, ".history.") && strings.HasSuffix(name, ".json"):
+			problems = append(problems, trash.NewProblem(trash.ProblemStaleFile, path,
+				"leftover temporary file",
+				func(trash.OrphanAction) error {
+					return os.RemoveAll(path)
+				}))
+			return nil
+		case d.IsDir() && (path == s.treeRoot() || s.isInTree(path)):
+			// Which directories of the tree layout were trashed is unknown,
+			// so their files are reported one by one, each with its original path
+			return nil
+		case d.IsDir() && datePathDepth(s.root, path) < 5:
+			// Directories of the date layout (~/.gomi/2006/01/02/<id>) hold the trashed files
+			return nil
+		}
+
+		if d.IsDir() {
+			// Directories left empty by older versions are harmless
+			entries, err := os.ReadDir(path)
+			if err == nil && len(entries) == 0 {
+				return filepath.SkipDir
+			}
+		}
+		problems = append(problems, trash.NewProblem(trash.ProblemOrphanFile, path,
+			"not recorded in history",
+			func(action trash.OrphanAction) error {
+				return s.fixOrphan(path, action)
+			}))
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", s.root, err)
+	}
+
+	return append(problems, s.checkLayout()...), nil
+}
+
+// checkBackup describes why the history backup is stale, or returns an empty string
+func (s *Storage) checkBackup(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	var backup history.History
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return fmt.Sprintf("cannot be parsed: %v", err)
+	}
+	if len(backup.Files) != len(s.history.Files) {
+		return fmt.Sprintf("has %d files while history has %d", len(backup.Files), len(s.history.Files))
+	}
+	// Compare the encoded entries, as decoded timestamps may differ in location only
+	got, _ := json.Marshal(backup.Files)
+	want, _ := json.Marshal(s.history.Files)
+	if !bytes.Equal(got, want) {
+		return "differs from history"
+	}
+	return ""
+}
+
+// dropHistoryEntry removes the entry from history and saves it
+func (s *Storage) dropHistoryEntry(target history.File) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for i, f := range s.history.Files {
+		if f.ID == target.ID && f.To == target.To && f.Timestamp.Equal(target.Timestamp) {
+			s.history.Files = append(s.history.Files[:i], s.history.Files[i+1:]...)
+			if err := s.saveHistory(); err != nil {
+				return err
+			}
+			s.collectBlobs(f.Blobs)
+			return nil
+		}
+	}
+	return nil
+}
+
+// fixOrphan adds the file to history or deletes it.
+// The original path is recovered from the tree layout, and otherwise
+// the file is restored to the home directory.
+func (s *Storage) fixOrphan(path string, action trash.OrphanAction) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Another process may have recorded the file since the check
+	for _, f := range s.history.Files {
+		if f.To == path {
+			return nil
+		}
+	}
+
+	if action == trash.OrphanDelete {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		s.removeEmptyParents(path)
+		return nil
+	}
+
+	name := filepath.Base(path)
+	id := filepath.Base(filepath.Dir(path))
+	var from string
+	if s.isInTree(path) {
+		rel := strings.TrimPrefix(path, s.treeRoot())
+		from = filepath.FromSlash(versionSuffix.ReplaceAllString(filepath.ToSlash(rel), "$1"))
+		name = filepath.Base(from)
+		id = uuid.New().String()
+	} else {
+		// ~/.gomi/2006/01/02/<id>/<name>.<id>
+		if trimmed, ok := strings.CutSuffix(name, "."+id); ok {
+			name = trimmed
+		} else {
+			id = uuid.New().String()
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		from = filepath.Join(home, name)
+	}
+
+	s.history.Add(history.File{
+		Name:      name,
+		ID:        id,
+		RunID:     s.config.RunID,
+		From:      from,
+		To:        path,
+		Timestamp: time.Now(),
+	})
+	return s.saveHistory()
+}
+
+// datePathDepth returns the number of path components of path under root
+func datePathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return 0
+	}
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
@@ -0,0 +1,111 @@
+package legacy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+)
+
+func TestCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for legacy doctor")
+	}
+
+	root := t.TempDir()
+	s := &Storage{root: root, historyPath: filepath.Join(root, history.Filename)}
+
+	write := func(rel string) string {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	kept := write("2026/10/17/id1/a.txt.id1")
+	orphan := write("2026/10/17/id2/b.txt.id2")
+	treeOrphan := write("tree/home/me/src.~1~/x.go.~2~")
+	tmp := write(".history.123.json")
+	tmpCopy := write("2026/10/17/id1/.a.txt.0b5c2c4e-8f1a-4a55-9a3e-2f4d1c6b7a80.tmp")
+	// A trashed file under a directory named like a temporary file
+	lookalike := write("tree/home/me/.0b5c2c4e-8f1a-4a55-9a3e-2f4d1c6b7a80.tmp/x")
+	if err := os.MkdirAll(filepath.Join(root, "2026/10/16/empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	s.history.Files = []history.File{
+		{ID: "id1", To: kept},
+		{ID: "id1", To: kept},
+		{ID: "id3", To: filepath.Join(root, "2026/10/17/id3/c.txt.id3")},
+		{ID: "id4", From: "/home/me/.0b5c2c4e-8f1a-4a55-9a3e-2f4d1c6b7a80.tmp/x", To: lookalike},
+	}
+	if err := s.saveHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]trash.ProblemKind{}
+	for _, p := range problems {
+		got[p.Path] = p.Kind
+	}
+	want := map[string]trash.ProblemKind{
+		kept:                  trash.ProblemDuplicate,
+		s.history.Files[2].To: trash.ProblemOrphanInfo,
+		orphan:                trash.ProblemOrphanFile,
+		treeOrphan:            trash.ProblemOrphanFile,
+		tmp:                   trash.ProblemStaleFile,
+		tmpCopy:               trash.ProblemStaleFile,
+		// preserve_paths is disabled
+		lookalike: trash.ProblemLayout,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Check() found %v, want %v", got, want)
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("Check() reported %q as %q, want %q", path, got[path], kind)
+		}
+	}
+
+	for _, p := range problems {
+		if err := p.Fix(trash.OrphanAdopt); err != nil {
+			t.Fatalf("failed to fix %s: %v", p, err)
+		}
+	}
+	// Orphans are adopted where they are, so the next run moves them to the configured layout
+	if problems, _ := s.Check(); len(problems) != 1 || problems[0].Path != treeOrphan || problems[0].Kind != trash.ProblemLayout {
+		t.Errorf("problems left after fixing: %v", problems)
+	}
+
+	from := map[string]history.File{}
+	for _, f := range s.history.Files {
+		from[f.To] = f
+	}
+	if f := from[orphan]; f.Name != "b.txt" || f.ID != "id2" {
+		t.Errorf("adopted %q as %+v", orphan, f)
+	}
+	for _, f := range s.history.Files {
+		if f.ID != "id4" {
+			continue
+		}
+		if s.isInTree(f.To) {
+			t.Errorf("%s is not moved to the date layout", f.To)
+		}
+		if _, err := os.Lstat(f.To); err != nil {
+			t.Errorf("%s is removed: %v", lookalike, err)
+		}
+	}
+	if f := from[treeOrphan]; f.From != "/home/me/src/x.go" {
+		t.Errorf("adopted %q from %q, want %q", treeOrphan, f.From, "/home/me/src/x.go")
+	}
+}
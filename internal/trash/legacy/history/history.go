@@ -9,7 +9,7 @@ import (
 	"time"
 
 	"github.com/babarot/gomi/internal/config"
-	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/utils/fs"
 	"github.com/k0kubun/pp/v3"
 	"github.com/rs/xid"
 )
@@ -36,6 +36,37 @@ type File struct {
 	From      string    `json:"from"`
 	To        string    `json:"to"`
 	Timestamp time.Time `json:"timestamp"`
+
+	// Compression is how the file is compressed in the trash, empty if not compressed
+	Compression string `json:"compression,omitempty"`
+
+	// OriginalSize is the size of the file before compression
+	OriginalSize int64 `json:"original_size,omitempty"`
+
+	// Metadata is the ownership, mode, times and extended attributes of the file
+	// before it was moved to trash
+	Metadata *fs.Metadata `json:"metadata,omitempty"`
+
+	// Checksum is the checksum of the file as stored in trash
+	Checksum string `json:"checksum,omitempty"`
+
+	// Blobs are the files of the entry whose content is shared with other
+	// entries through the blob store, if deduplication is enabled
+	Blobs []Blob `json:"blobs,omitempty"`
+}
+
+// Blob is a file of an entry hardlinked to a content-addressed blob
+type Blob struct {
+	// Path is the path of the file relative to the entry ("." for the entry itself)
+	Path string `json:"path"`
+
+	// Digest is the checksum of the content, which addresses the blob
+	Digest string `json:"digest"`
+
+	Size int64 `json:"size"`
+
+	// Metadata is the metadata of the file, as its inode is shared with the blob
+	Metadata *fs.Metadata `json:"metadata,omitempty"`
 }
 
 func (f File) GetName() string {
@@ -50,6 +81,10 @@ func (f File) GetDeletedAt() time.Time {
 	return f.Timestamp
 }
 
+func (f File) GetSize() (int64, error) {
+	return fs.DirSize(f.To)
+}
+
 func New(home string, c config.History) History {
 	if home == "" {
 		home = filepath.Join(os.Getenv("HOME"), ".gomi")
@@ -178,15 +213,6 @@ func (h *History) setVersion() {
 	}
 }
 
-func (h History) Filter() []File {
-	opts := trash.FilterOptions{
-		Include: h.config.Include,
-		Exclude: h.config.Exclude,
-	}
-	slog.Debug("legacy filter items", "len(files)", len(h.Files))
-	return trash.Filter(h.Files, opts)
-}
-
 func (h History) FileInfo(runID string, arg string) (File, error) {
 	name := filepath.Base(arg)
 	from, err := filepath.Abs(arg)
@@ -0,0 +1,87 @@
+package legacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/journal"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+)
+
+// recoverJournal completes or rolls back the moves of files interrupted by a crash or a kill,
+// and brings the history in line with them. The history lock must be held.
+func (s *Storage) recoverJournal() {
+	entries, err := s.journal.Pending(trash.StorageTypeLegacy.String())
+	if err != nil {
+		slog.Warn("failed to read journal", "error", err)
+		return
+	}
+
+	var (
+		resolved []*journal.Entry
+		collect  []history.Blob
+	)
+	for _, e := range entries {
+		done, err := e.Resolve()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to recover interrupted %s: %v\n", e, err)
+			s.journal.Release(e)
+			continue
+		}
+
+		switch {
+		case e.Op == journal.OpPut && done:
+			var file history.File
+			if err := json.Unmarshal(e.Record, &file); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to recover interrupted %s: %v\n", e, err)
+				s.journal.Release(e)
+				continue
+			}
+			if s.history.FindByID(file.ID) == nil {
+				s.history.Add(file)
+			}
+		case e.Op == journal.OpPut && !done:
+			s.removeEmptyParents(e.Dst)
+		case e.Op == journal.OpMove && done:
+			var file history.File
+			if err := json.Unmarshal(e.Record, &file); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to recover interrupted %s: %v\n", e, err)
+				s.journal.Release(e)
+				continue
+			}
+			if entry := s.findEntry(e.Src); entry != nil && entry.ID == file.ID {
+				entry.To = e.Dst
+			}
+			s.removeEmptyParents(e.Src)
+		case e.Op == journal.OpMove && !done:
+			s.removeEmptyParents(e.Dst)
+		case e.Op == journal.OpRestore && done:
+			if entry := s.findEntry(e.Src); entry != nil {
+				collect = append(collect, entry.Blobs...)
+			}
+			s.history.RemoveByPath(e.Src)
+			s.removeEmptyParents(e.Src)
+		}
+		resolved = append(resolved, e)
+	}
+	if len(resolved) == 0 {
+		return
+	}
+
+	// The entries are kept until the history reflects them
+	if err := s.saveHistory(); err != nil {
+		slog.Warn("failed to save history", "error", err)
+		for _, e := range resolved {
+			s.journal.Release(e)
+		}
+		return
+	}
+	for _, e := range resolved {
+		s.journal.Commit(e)
+		slog.Info("recovered interrupted operation", "entry", e.String())
+	}
+	s.collectBlobs(collect)
+}
@@ -0,0 +1,82 @@
+package legacy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+)
+
+// retiredSuffix is appended to the history once every file has been migrated
+const retiredSuffix = ".migrated"
+
+// Forget removes a file that has been moved to another storage from history.
+// It refuses to forget a file that is still in the trash.
+func (s *Storage) Forget(file *trash.File) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return trash.NewStorageError("forget", file.TrashPath, err)
+	}
+	defer unlock()
+
+	if _, err := os.Lstat(file.TrashPath); err == nil {
+		return trash.NewStorageError("forget", file.TrashPath, errors.New("file is still in the trash"))
+	}
+	var blobs []history.Blob
+	if entry := s.findEntry(file.TrashPath); entry != nil {
+		blobs = entry.Blobs
+	}
+	s.removeEmptyParents(file.TrashPath)
+	s.history.RemoveByPath(file.TrashPath)
+	if err := s.saveHistory(); err != nil {
+		return trash.NewStorageError("forget", file.TrashPath, fmt.Errorf("failed to save history: %w", err))
+	}
+	s.collectBlobs(blobs)
+	return nil
+}
+
+// Detach gives the deduplicated files of a file in the trash a copy of their own,
+// so that it can be moved to another storage without taking the blobs along
+func (s *Storage) Detach(file *trash.File) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return trash.NewStorageError("detach", file.TrashPath, err)
+	}
+	defer unlock()
+
+	entry := s.findEntry(file.TrashPath)
+	if entry == nil {
+		return nil
+	}
+	if err := s.unlinkBlobs(file.TrashPath, entry.Blobs); err != nil {
+		return trash.NewStorageError("detach", file.TrashPath, err)
+	}
+	return nil
+}
+
+// Retire renames the history to history.json.migrated and removes its backup,
+// so that the storage is no longer used. The history must be empty.
+func (s *Storage) Retire() error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if n := len(s.history.Files); n > 0 {
+		return fmt.Errorf("cannot retire legacy history: %d files are left", n)
+	}
+	if err := os.Rename(s.historyPath, s.historyPath+retiredSuffix); err != nil {
+		return fmt.Errorf("failed to retire legacy history: %w", err)
+	}
+	if err := os.Remove(s.historyPath + ".backup"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove history backup: %w", err)
+	}
+	// Every blob has been collected with the files pointing at it
+	os.Remove(s.blobsRoot())
+	slog.Info("retired legacy history", "path", s.historyPath+retiredSuffix)
+	return nil
+}
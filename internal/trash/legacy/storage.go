@@ -6,9 +6,12 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/compress"
+	"github.com/babarot/gomi/internal/trash/journal"
 	"github.com/babarot/gomi/internal/trash/legacy/history"
 	"github.com/babarot/gomi/internal/utils/fs"
 	"github.com/babarot/gomi/internal/utils/log"
@@ -28,8 +31,18 @@ type Storage struct {
 
 	// In-memory cache of trash history
 	history history.History
+
+	// journal records files being moved in and out of trash
+	journal *journal.Journal
+
+	// mu serializes changes to history within the process,
+	// while the history lock does across processes
+	mu sync.Mutex
 }
 
+// lockSuffix is appended to the history path to get its lock file
+const lockSuffix = ".lock"
+
 // NewStorage creates a new legacy storage instance
 func NewStorage(cfg trash.Config) (trash.Storage, error) {
 	slog.Info(log.UnderBold("initialize legacy storage"))
@@ -49,7 +62,6 @@ func NewStorage(cfg trash.Config) (trash.Storage, error) {
 		root:        root,
 		config:      cfg,
 		historyPath: filepath.Join(root, history.Filename),
-		history:     history.New(cfg.GomiDir, cfg.History),
 	}
 	slog.Debug("legacy storage",
 		"gomiDir", cfg.GomiDir,
@@ -61,10 +73,22 @@ func NewStorage(cfg trash.Config) (trash.Storage, error) {
 	}
 
 	// Load history
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	if err := s.loadHistory(); err != nil {
 		return nil, fmt.Errorf("failed to load history: %w", err)
 	}
 
+	// Complete or roll back the moves interrupted by a crash or a kill
+	s.journal, err = journal.Open(cfg.JournalDir)
+	if err != nil {
+		slog.Warn("journaling is disabled", "error", err)
+	}
+	s.recoverJournal()
+
 	return s, nil
 }
 
@@ -84,33 +108,123 @@ func (s *Storage) Put(src string) error {
 		return trash.NewStorageError("put", src, err)
 	}
 
+	// Read the metadata first, as compressing the file changes its access time
+	metadata, err := fs.ReadMetadata(abs)
+	if err != nil {
+		return trash.NewStorageError("put", src, err)
+	}
+
+	// Compressing can take a while, so it is done before taking the lock.
+	// The compressed copy is written in the root to be on the same device as the trash path.
+	compressed := s.config.Compress(abs, s.root)
+
+	// So is hashing the file, and finding its contents to deduplicate
+	var (
+		sum   string
+		blobs []history.Blob
+	)
+	if compressed != nil {
+		sum = compressed.Checksum
+	} else {
+		sum = s.config.Sum(abs)
+		blobs = s.scanBlobs(abs)
+	}
+
+	// The trash path is chosen from history, so the lock is held until the file
+	// is recorded in it, and other processes never pick the same path
+	unlock, err := s.lockHistory()
+	if err != nil {
+		if compressed != nil {
+			compressed.Discard()
+		}
+		return trash.NewStorageError("put", src, err)
+	}
+	defer unlock()
+
 	id := uuid.New().String()
-	trashName := fmt.Sprintf("%s.%s", filepath.Base(abs), id)
-	trashPath := filepath.Join(s.root, time.Now().Format("2006/01/02"), id, trashName)
+	now := time.Now()
+	trashPath := s.trashPathFor(abs, id, now)
 
 	// Create parent directories
 	if err := os.MkdirAll(filepath.Dir(trashPath), 0700); err != nil {
+		if compressed != nil {
+			compressed.Discard()
+		}
 		return trash.NewStorageError("put", src, err)
 	}
 
+	file := history.File{
+		Name:      filepath.Base(abs),
+		ID:        id,
+		RunID:     s.config.RunID,
+		From:      abs,
+		To:        trashPath,
+		Timestamp: now,
+		Metadata:  metadata,
+		Checksum:  sum,
+	}
+	if compressed != nil {
+		file.Compression = compressed.Method.String()
+		file.OriginalSize = compressed.Size
+	}
+
+	// Record the move, so that it is completed or rolled back if gomi is killed meanwhile
+	record, err := json.Marshal(file)
+	if err != nil {
+		if compressed != nil {
+			compressed.Discard()
+		}
+		return trash.NewStorageError("put", src, err)
+	}
+	entry := &journal.Entry{
+		Op:      journal.OpPut,
+		Storage: trash.StorageTypeLegacy.String(),
+		Src:     abs,
+		Dst:     trashPath,
+		Record:  record,
+	}
+	if err := s.journal.Begin(entry); err != nil {
+		if compressed != nil {
+			compressed.Discard()
+		}
+		return trash.NewStorageError("put", src, err)
+	}
+	defer s.journal.Commit(entry)
+
 	// Move file to trash
-	if err := fs.Move(abs, trashPath, false); err != nil {
+	if compressed != nil {
+		if err := compressed.Commit(trashPath); err != nil {
+			return trash.NewStorageError("put", src, err)
+		}
+		if err := s.journal.Checkpoint(entry, journal.StepCopied); err != nil {
+			os.Remove(trashPath)
+			return trash.NewStorageError("put", src, err)
+		}
+	} else if err := fs.Move(abs, trashPath, false); err != nil {
 		return trash.NewStorageError("put", src, err)
 	}
 
+	// Files that failed to be deduplicated are kept as they are.
+	// The blobs are not in the journal record, as a file is not linked until then.
+	file.Blobs = s.linkBlobs(trashPath, blobs)
+
 	// Add to history
-	s.history.Add(history.File{
-		Name:      filepath.Base(abs),
-		ID:        id,
-		RunID:     id, // For compatibility with old format
-		From:      abs,
-		To:        trashPath,
-		Timestamp: time.Now(),
-	})
+	s.history.Add(file)
 
 	// Save history
 	if err := s.saveHistory(); err != nil {
-		// Try to roll back the file move
+		if compressed != nil {
+			// The original is still in place
+			os.Remove(trashPath)
+			return trash.NewStorageError(
+				"put",
+				src,
+				fmt.Errorf("failed to save history: %w", err))
+		}
+		// Try to roll back the file move, with files of its own
+		if err := s.unlinkBlobs(trashPath, file.Blobs); err != nil {
+			slog.Warn("failed to copy deduplicated files for rollback", "error", err)
+		}
 		if moveErr := fs.Move(trashPath, abs, false); moveErr != nil {
 			return trash.NewStorageError(
 				"put",
@@ -123,19 +237,30 @@ func (s *Storage) Put(src string) error {
 			fmt.Errorf("failed to save history: %w", err))
 	}
 
+	// The compressed copy is recorded in history, so the original can be removed
+	if compressed != nil {
+		if err := os.RemoveAll(abs); err != nil {
+			return trash.NewStorageError("put", src, fmt.Errorf("compressed into trash but failed to remove original: %w", err))
+		}
+	}
+
 	return nil
 }
 
 func (s *Storage) List() ([]*trash.File, error) {
 	var files []*trash.File
 
-	for _, f := range s.history.Filter() {
+	for _, f := range s.history.Files {
 		// Convert legacy File to trash.File
 		file := &trash.File{
 			Name:         f.Name,
 			OriginalPath: f.From,
 			TrashPath:    f.To,
 			DeletedAt:    f.Timestamp,
+			RunID:        f.RunID,
+			Compression:  f.Compression,
+			Metadata:     f.Metadata,
+			Checksum:     f.Checksum,
 		}
 
 		// Get additional file info
@@ -144,6 +269,17 @@ func (s *Storage) List() ([]*trash.File, error) {
 			file.IsDir = info.IsDir()
 			file.FileMode = info.Mode()
 		}
+		if m, err := compress.ParseMethod(f.Compression); err == nil {
+			file.Size = f.OriginalSize
+			file.IsDir = m.Tar
+		}
+		if f.Metadata != nil {
+			// The mode in the trash is the one of the compressed file, if compressed
+			file.FileMode = f.Metadata.Mode
+			if file.IsDir {
+				file.FileMode |= os.ModeDir
+			}
+		}
 
 		file.SetStorage(s)
 		files = append(files, file)
@@ -157,16 +293,49 @@ func (s *Storage) Restore(file *trash.File, dst string) error {
 		dst = file.OriginalPath
 	}
 
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return trash.NewStorageError("restore", dst, err)
+	}
+	defer unlock()
+
 	// Ensure destination directory exists
 	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 		return trash.NewStorageError("restore", dst, err)
 	}
 
-	// Move file back
-	if err := fs.Move(file.TrashPath, dst, false); err != nil {
+	// Deduplicated files are restored as copies, not as links to the blobs
+	var blobs []history.Blob
+	if entry := s.findEntry(file.TrashPath); entry != nil {
+		blobs = entry.Blobs
+	}
+	if err := s.unlinkBlobs(file.TrashPath, blobs); err != nil {
 		return trash.NewStorageError("restore", dst, err)
 	}
 
+	// Record the move, so that it is completed or rolled back if gomi is killed meanwhile
+	entry := &journal.Entry{
+		Op:      journal.OpRestore,
+		Storage: trash.StorageTypeLegacy.String(),
+		Src:     file.TrashPath,
+		Dst:     journal.StagingPath(dst),
+		Target:  dst,
+	}
+	if err := s.journal.Begin(entry); err != nil {
+		return trash.NewStorageError("restore", dst, err)
+	}
+	defer s.journal.Commit(entry)
+	copied := func() error {
+		return s.journal.Checkpoint(entry, journal.StepCopied)
+	}
+
+	// Move file back, decompressing it if needed
+	if err := trash.MoveOut(file, entry.Dst, dst, false, copied); err != nil {
+		return trash.NewStorageError("restore", dst, err)
+	}
+
+	s.removeEmptyParents(file.TrashPath)
+
 	// Remove from history
 	s.history.RemoveByPath(file.TrashPath)
 
@@ -174,15 +343,28 @@ func (s *Storage) Restore(file *trash.File, dst string) error {
 	if err := s.saveHistory(); err != nil {
 		return trash.NewStorageError("restore", dst, fmt.Errorf("failed to save history: %w", err))
 	}
+	s.collectBlobs(blobs)
 
 	return nil
 }
 
 func (s *Storage) Remove(file *trash.File) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return trash.NewStorageError("remove", file.TrashPath, err)
+	}
+	defer unlock()
+
+	var blobs []history.Blob
+	if entry := s.findEntry(file.TrashPath); entry != nil {
+		blobs = entry.Blobs
+	}
+
 	// Remove the actual file
 	if err := os.RemoveAll(file.TrashPath); err != nil {
 		return trash.NewStorageError("remove", file.TrashPath, err)
 	}
+	s.removeEmptyParents(file.TrashPath)
 
 	// Remove from history
 	s.history.RemoveByPath(file.TrashPath)
@@ -192,36 +374,70 @@ func (s *Storage) Remove(file *trash.File) error {
 		return trash.NewStorageError("remove", file.TrashPath, fmt.Errorf("failed to save history: %w", err))
 	}
 
+	// Blobs are removed once no other file points at them
+	s.collectBlobs(blobs)
+
 	return nil
 }
 
-func (s *Storage) loadHistory() error {
-	if err := s.history.Open(); err != nil {
-		slog.Error("failed to open legacy history", "error", err)
-		return err
-	}
-
-	/* TODO: Remove this logic or keep instead of s.history.Open()
-
-	if _, err := os.Stat(s.historyPath); os.IsNotExist(err) {
-		// No history file yet, start with empty history
-		return nil
+// lock locks the history against other gomi processes and goroutines
+func (s *Storage) lock() (func(), error) {
+	s.mu.Lock()
+	unlock, err := fs.Lock(s.historyPath + lockSuffix)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to lock history: %w", err)
 	}
+	return func() {
+		unlock()
+		s.mu.Unlock()
+	}, nil
+}
 
-	f, err := os.Open(s.historyPath)
+// lockHistory locks the history and reloads it, so that changes are applied
+// on top of the ones saved by other processes since it was loaded.
+// The history must be saved before the returned function releases the lock.
+func (s *Storage) lockHistory() (func(), error) {
+	unlock, err := s.lock()
 	if err != nil {
-		return fmt.Errorf("failed to open history file: %w", err)
+		return nil, err
+	}
+	if err := s.reloadHistory(); err != nil {
+		unlock()
+		return nil, err
 	}
-	defer f.Close()
+	return unlock, nil
+}
 
-	if err := json.NewDecoder(f).Decode(&s.history); err != nil {
-		return fmt.Errorf("failed to decode history file: %w", err)
+// loadHistory opens the history, restoring it from its backup if needed
+func (s *Storage) loadHistory() error {
+	h := history.New(s.root, s.config.History)
+	if err := h.Open(); err != nil {
+		slog.Error("failed to open legacy history", "error", err)
+		return err
 	}
-	*/
+	s.history = h
+	return nil
+}
 
+// reloadHistory reads the history as it is on disk.
+// Unlike loadHistory, the backup is left as it was when the history was loaded.
+func (s *Storage) reloadHistory() error {
+	h := history.New(s.root, s.config.History)
+	data, err := os.ReadFile(s.historyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &h); err != nil {
+			return fmt.Errorf("failed to decode history: %w", err)
+		}
+	}
+	s.history = h
 	return nil
 }
 
+// saveHistory writes the history atomically. The history lock must be held.
 func (s *Storage) saveHistory() error {
 	// Create history file atomically using a temporary file
 	tmp, err := os.CreateTemp(filepath.Dir(s.historyPath), ".history.*.json")
@@ -0,0 +1,72 @@
+package legacy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/babarot/gomi/internal/trash"
+)
+
+func TestPutConcurrent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for legacy storage")
+	}
+
+	for _, preservePaths := range []bool{false, true} {
+		t.Run(fmt.Sprintf("preserve_paths=%v", preservePaths), func(t *testing.T) {
+			cfg := trash.Config{GomiDir: t.TempDir(), PreservePaths: preservePaths}
+			src := t.TempDir()
+
+			// Each storage stands for a separate gomi process
+			const procs, files = 4, 10
+			var wg sync.WaitGroup
+			for p := range procs {
+				storage, err := NewStorage(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range files {
+						path := filepath.Join(src, fmt.Sprintf("%d-%d", p, i))
+						if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+							t.Error(err)
+							return
+						}
+						if err := storage.Put(path); err != nil {
+							t.Error(err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			storage, err := NewStorage(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := storage.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != procs*files {
+				t.Errorf("got %d files in history, want %d", len(got), procs*files)
+			}
+			seen := make(map[string]bool)
+			for _, f := range got {
+				if seen[f.TrashPath] {
+					t.Errorf("%s is recorded more than once", f.TrashPath)
+				}
+				seen[f.TrashPath] = true
+				if _, err := os.Lstat(f.TrashPath); err != nil {
+					t.Errorf("%s is missing from the trash", f.TrashPath)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package legacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/journal"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+	"github.com/babarot/gomi/internal/utils/fs"
+)
+
+// treeDirname is the directory mirroring original paths when PreservePaths is enabled,
+// e.g. ~/.gomi/tree/home/me/src/x.go
+const treeDirname = "tree"
+
+// treeRoot returns the root of the mirrored layout
+func (s *Storage) treeRoot() string {
+	return filepath.Join(s.root, treeDirname)
+}
+
+// isInTree reports whether the trash path uses the mirrored layout
+func (s *Storage) isInTree(path string) bool {
+	return strings.HasPrefix(path, s.treeRoot()+string(filepath.Separator))
+}
+
+// trashPathFor returns where the file is stored according to the configured layout
+func (s *Storage) trashPathFor(abs, id string, deletedAt time.Time) string {
+	if s.config.PreservePaths {
+		return s.treePath(abs)
+	}
+	return s.datePath(abs, id, deletedAt)
+}
+
+// datePath returns the path in the default layout:
+// ~/.gomi/2006/01/02/<id>/<name>.<id>
+func (s *Storage) datePath(abs, id string, deletedAt time.Time) string {
+	name := fmt.Sprintf("%s.%s", filepath.Base(abs), id)
+	return filepath.Join(s.root, deletedAt.Format("2006/01/02"), id, name)
+}
+
+// treePath returns a free path mirroring abs under the tree root.
+// Versions of the same path are kept side by side as "x.go", "x.go.~1~", "x.go.~2~" and so on.
+// A trashed file is never used as a parent directory, so a component
+// clashing with one takes the next version suffix as well.
+func (s *Storage) treePath(abs string) string {
+	rel := strings.TrimPrefix(filepath.Clean(abs), filepath.VolumeName(abs))
+	parts := strings.Split(strings.Trim(rel, string(filepath.Separator)), string(filepath.Separator))
+
+	entries := make(map[string]bool, len(s.history.Files))
+	for _, f := range s.history.Files {
+		entries[f.To] = true
+	}
+
+	path := s.treeRoot()
+	for i, part := range parts {
+		last := i == len(parts)-1
+		for n := 0; ; n++ {
+			name := part
+			if n > 0 {
+				name = fmt.Sprintf("%s.~%d~", part, n)
+			}
+			candidate := filepath.Join(path, name)
+			fi, err := os.Lstat(candidate)
+			if last {
+				if os.IsNotExist(err) && !entries[candidate] {
+					path = candidate
+					break
+				}
+				continue
+			}
+			// An intermediate directory can be shared with other trashed files
+			if !entries[candidate] && (os.IsNotExist(err) || err == nil && fi.IsDir()) {
+				path = candidate
+				break
+			}
+		}
+	}
+	return path
+}
+
+// removeEmptyParents removes directories left empty after a file is
+// restored, removed or migrated, up to the root of the storage
+func (s *Storage) removeEmptyParents(path string) {
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, s.root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// os.Remove fails on non-empty directories, which ends the cleanup
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// checkLayout reports the entries of history stored in another layout than the
+// configured one, which "gomi doctor --fix" moves once PreservePaths is switched
+func (s *Storage) checkLayout() []trash.Problem {
+	var problems []trash.Problem
+	for _, f := range s.history.Files {
+		if s.isInTree(f.To) == s.config.PreservePaths {
+			continue
+		}
+		if _, err := os.Lstat(f.To); err != nil {
+			// The file is missing from the trash, which is reported as such
+			continue
+		}
+		detail := "stored in the date layout, but preserve_paths is enabled"
+		if !s.config.PreservePaths {
+			detail = "stored in the tree layout, but preserve_paths is disabled"
+		}
+		id := f.ID
+		problems = append(problems, trash.NewProblem(trash.ProblemLayout, f.To, detail,
+			func(trash.OrphanAction) error {
+				return s.relocate(id)
+			}))
+	}
+	return problems
+}
+
+// relocate moves the entry to the configured layout. The move is journaled and
+// the history is saved right after it, so that the entry never points at a file
+// that has been moved.
+func (s *Storage) relocate(id string) error {
+	unlock, err := s.lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	i := slices.IndexFunc(s.history.Files, func(f history.File) bool { return f.ID == id })
+	if i < 0 || s.isInTree(s.history.Files[i].To) == s.config.PreservePaths {
+		// Removed or moved meanwhile
+		return nil
+	}
+	f := s.history.Files[i]
+	src := f.To
+	f.To = s.trashPathFor(f.From, f.ID, f.Timestamp)
+	if err := os.MkdirAll(filepath.Dir(f.To), 0700); err != nil {
+		return err
+	}
+
+	record, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	entry := &journal.Entry{
+		Op:      journal.OpMove,
+		Storage: trash.StorageTypeLegacy.String(),
+		Src:     src,
+		Dst:     f.To,
+		Record:  record,
+	}
+	if err := s.journal.Begin(entry); err != nil {
+		return err
+	}
+	defer s.journal.Commit(entry)
+
+	if err := fs.Move(src, f.To, false); err != nil {
+		return fmt.Errorf("failed to move %s: %w", src, err)
+	}
+	s.history.Files[i] = f
+	if err := s.saveHistory(); err != nil {
+		// Put the file back where the history on disk points
+		s.history.Files[i].To = src
+		if err := os.Rename(f.To, src); err != nil {
+			return fmt.Errorf("failed to save history and to move %s back from %s: %w", src, f.To, err)
+		}
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	s.removeEmptyParents(src)
+	slog.Debug("moved to the configured layout", "from", src, "to", f.To)
+	return nil
+}
@@ -0,0 +1,109 @@
+package legacy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/legacy/history"
+)
+
+func TestTreePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for tree layout")
+	}
+
+	root := t.TempDir()
+	s := &Storage{root: root, config: trash.Config{PreservePaths: true}}
+	tree := filepath.Join(root, treeDirname)
+
+	put := func(abs string) string {
+		path := s.treePath(abs)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		s.history.Files = append(s.history.Files, history.File{From: abs, To: path})
+		return path
+	}
+
+	tests := []struct {
+		abs  string
+		want string
+	}{
+		{"/home/me/src/x.go", "home/me/src/x.go"},
+		{"/home/me/src/x.go", "home/me/src/x.go.~1~"},
+		{"/home/me/src/x.go", "home/me/src/x.go.~2~"},
+		// "src" is an intermediate directory, so the trashed directory takes a version
+		{"/home/me/src", "home/me/src.~1~"},
+		// "src.~1~" is a trashed file, so it is never used as a parent
+		{"/home/me/src.~1~/y.go", "home/me/src.~1~.~1~/y.go"},
+	}
+
+	for _, tt := range tests {
+		got := put(tt.abs)
+		want := filepath.Join(tree, filepath.FromSlash(tt.want))
+		if got != want {
+			t.Errorf("treePath(%q) = %q, want %q", tt.abs, got, want)
+		}
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for tree layout")
+	}
+
+	root := t.TempDir()
+	s := &Storage{root: root, historyPath: filepath.Join(root, history.Filename)}
+	deletedAt := time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local)
+	path := s.datePath("/home/me/src/x.go", "id1", deletedAt)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.history.Files = []history.File{{ID: "id1", From: "/home/me/src/x.go", To: path, Timestamp: deletedAt}}
+	if err := s.saveHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching the layout moves nothing until the problem is fixed
+	s.config.PreservePaths = true
+	problems, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Kind != trash.ProblemLayout || problems[0].Path != path {
+		t.Fatalf("Check() = %v, want %s in the other layout", problems, path)
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Fatalf("%s is moved before fixing: %v", path, err)
+	}
+
+	if err := problems[0].Fix(trash.OrphanAdopt); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(root, treeDirname, "home/me/src/x.go")
+	if err := s.reloadHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.history.Files[0].To; got != want {
+		t.Errorf("history points at %s, want %s", got, want)
+	}
+	if _, err := os.Lstat(want); err != nil {
+		t.Errorf("%s is not moved: %v", path, err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "2026")); !os.IsNotExist(err) {
+		t.Errorf("empty directories of %s are left", path)
+	}
+	if problems, _ := s.Check(); len(problems) != 0 {
+		t.Errorf("problems left after fixing: %v", problems)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/babarot/gomi/internal/utils/log"
 )
@@ -33,6 +35,13 @@ type Manager struct {
 	storages []Storage
 	config   Config
 	strategy Strategy
+
+	// startedAt is when this manager was created. Files trashed after this
+	// time belong to the current invocation and are never evicted by retention.
+	startedAt time.Time
+
+	// retentionOnce enforces the retention policy once per run, as it scans the whole trash
+	retentionOnce sync.Once
 }
 
 // ManagerOption is a function type for configuring Manager
@@ -59,6 +68,8 @@ func NewManager(cfg Config, opts ...ManagerOption) (*Manager, error) {
 	m := &Manager{
 		config:   cfg,
 		storages: make([]Storage, 0),
+		// .trashinfo only records seconds, so truncate to compare fairly
+		startedAt: time.Now().Truncate(time.Second),
 	}
 
 	// Apply all provided options
@@ -98,7 +109,8 @@ func determineStrategy(storages []Storage) Strategy {
 	return StrategyAuto
 }
 
-// Put moves the file at src path to trash
+// Put moves the file at src path to trash, and then enforces the retention policy
+// the first time a file is put in this run
 func (m *Manager) Put(src string) error {
 	path, err := filepath.Abs(src)
 	if err != nil {
@@ -125,6 +137,7 @@ func (m *Manager) Put(src string) error {
 			} else {
 				slog.Debug("moved file to trash", "path", path)
 			}
+			m.retentionOnce.Do(m.enforceRetention)
 			return nil
 		}
 		lastErr = err
@@ -136,8 +149,26 @@ func (m *Manager) Put(src string) error {
 	return fmt.Errorf("all storage backends failed to put file: %w", lastErr)
 }
 
-// List returns all files from all storage backends
+// List returns all files from all storage backends,
+// filtered by the history configuration
 func (m *Manager) List() ([]*File, error) {
+	files, err := m.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := FilterOptions{
+		Include: m.config.History.Include,
+		Exclude: m.config.History.Exclude,
+	}
+	slog.Debug("manager filter items", "len(files)", len(files))
+	return Filter(files, opts), nil
+}
+
+// ListAll returns all files from all storage backends without applying
+// the history filters. This is used by operations that must see every
+// entry in the trash, such as permanently emptying it.
+func (m *Manager) ListAll() ([]*File, error) {
 	var allFiles []*File
 	var errs []error
 
@@ -166,36 +197,18 @@ func (m *Manager) List() ([]*File, error) {
 	return allFiles, nil
 }
 
-// Restore restores the given file
+// Restore restores the given file, after verifying it against its checksum
 func (m *Manager) Restore(file *File, dst string) error {
-	// Find the appropriate storage for this file
-	var targetStorage Storage
-	for _, storage := range m.storages {
-		slog.Debug("checking storage",
-			"file", strings.Join([]string{
-				"name: " + file.Name,
-				"trashPath: " + file.TrashPath,
-				"originalPath: " + file.OriginalPath,
-			}, "\n"),
-			"info", strings.Join([]string{
-				fmt.Sprintf("type: %s", storage.Info().Type),
-				"trashes: " + strings.Join(storage.Info().Trashes, ", "),
-			}, "\n"),
-		)
-		foundStorage := func() bool {
-			for _, trashRoot := range storage.Info().Trashes {
-				if strings.HasPrefix(file.TrashPath, trashRoot) {
-					return true
-				}
-			}
-			return false
-		}()
-		if foundStorage {
-			targetStorage = storage
-			break
-		}
+	if err := m.config.VerifyBeforeRestore(file); err != nil {
+		return err
 	}
+	return m.restore(file, dst)
+}
 
+// restore restores the given file without verifying it
+func (m *Manager) restore(file *File, dst string) error {
+	// Find the appropriate storage for this file
+	targetStorage := m.storageOf(file)
 	if targetStorage == nil {
 		return errors.New("file does not belong to any known storage")
 	}
@@ -215,22 +228,7 @@ func (m *Manager) Restore(file *File, dst string) error {
 // Remove permanently removes the file from trash
 func (m *Manager) Remove(file *File) error {
 	// Find the appropriate storage for this file
-	var targetStorage Storage
-	for _, storage := range m.storages {
-		foundStorage := func() bool {
-			for _, trashRoot := range storage.Info().Trashes {
-				if strings.HasPrefix(file.TrashPath, trashRoot) {
-					return true
-				}
-			}
-			return false
-		}()
-		if foundStorage {
-			targetStorage = storage
-			break
-		}
-	}
-
+	targetStorage := m.storageOf(file)
 	if targetStorage == nil {
 		return errors.New("file does not belong to any known storage")
 	}
@@ -238,6 +236,32 @@ func (m *Manager) Remove(file *File) error {
 	return targetStorage.Remove(file)
 }
 
+// storageOf returns the storage whose trash directory contains the file, or nil if none.
+// Trash directories can be anywhere (e.g., core.trash.home_dir), so one may be a
+// string prefix of another, like /scratch/Trash and /scratch/Trash-1000.
+// The path must be inside the directory, and the deepest directory wins.
+func (m *Manager) storageOf(file *File) Storage {
+	var (
+		found   Storage
+		longest int
+	)
+	for _, storage := range m.storages {
+		for _, trashRoot := range storage.Info().Trashes {
+			root := filepath.Clean(trashRoot)
+			if !strings.HasPrefix(file.TrashPath, root+string(filepath.Separator)) {
+				continue
+			}
+			if len(root) > longest {
+				found, longest = storage, len(root)
+			}
+		}
+	}
+	slog.Debug("storage of file",
+		"trashPath", file.TrashPath,
+		"found", found != nil)
+	return found
+}
+
 // ListStorages returns information about all available storage backends
 func (m *Manager) ListStorages() []*StorageInfo {
 	var infos []*StorageInfo
@@ -0,0 +1,46 @@
+package trash
+
+import (
+	"runtime"
+	"testing"
+)
+
+// fakeStorage is a Storage that only reports its trash directories
+type fakeStorage struct {
+	Storage
+	trashes []string
+}
+
+func (s *fakeStorage) Info() *StorageInfo {
+	return &StorageInfo{Trashes: s.trashes}
+}
+
+func TestManagerStorageOf(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for storage lookup")
+	}
+
+	xdg := &fakeStorage{trashes: []string{"/scratch/Trash", "/media/usb/.Trash-1000"}}
+	legacy := &fakeStorage{trashes: []string{"/scratch/Trash-legacy"}}
+	m := &Manager{storages: []Storage{xdg, legacy}}
+
+	tests := []struct {
+		path string
+		want Storage
+	}{
+		{"/scratch/Trash/files/a.txt", xdg},
+		{"/media/usb/.Trash-1000/files/a.txt", xdg},
+		{"/scratch/Trash-legacy/2026/10/17/id/a.txt.id", legacy},
+		{"/scratch/Trash", nil},
+		{"/scratch/Trashcan/a.txt", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := m.storageOf(&File{TrashPath: tt.path})
+			if got != tt.want {
+				t.Errorf("storageOf(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package trash
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// RestoreMetadata reapplies the metadata recorded for the file to its restored copy at dst,
+// as moving across devices, compression or other trash clients may not keep it.
+// The file is restored either way, so attributes that cannot be set are reported as a warning.
+func RestoreMetadata(file *File, dst string) {
+	if file.Metadata == nil {
+		return
+	}
+	if err := file.Metadata.Apply(dst); err != nil {
+		slog.Warn("failed to restore metadata", "path", dst, "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,32 @@
+package trash
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/babarot/gomi/internal/utils/fs"
+)
+
+// MoveOut moves the file out of the trash to dst, decompressing it if needed.
+// The file is moved to staging, a hidden sibling of dst recorded in the journal,
+// and renamed to dst once it is complete with its metadata, so that nothing the user
+// creates at dst meanwhile is ever removed. copied is called once a complete copy
+// of the file is at staging, before it is removed from the trash.
+func MoveOut(file *File, staging, dst string, fallbackCopy bool, copied func() error) error {
+	if file.IsCompressed() {
+		if err := DecompressCheckpoint(file, staging, copied); err != nil {
+			return err
+		}
+	} else if err := fs.MoveCheckpoint(file.TrashPath, staging, fallbackCopy, copied); err != nil {
+		return err
+	}
+	RestoreMetadata(file, staging)
+
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%w: %s, the restored file is left in %s", ErrFileExists, dst, staging)
+	}
+	if err := os.Rename(staging, dst); err != nil {
+		return fmt.Errorf("failed to restore to %s, the restored file is left in %s: %w", dst, staging, err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package trash
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/babarot/gomi/internal/config"
+	"github.com/docker/go-units"
+	"github.com/k1LoW/duration"
+)
+
+// retentionPolicy is the parsed form of config.RetentionConfig
+type retentionPolicy struct {
+	maxAge   time.Duration
+	maxSize  int64
+	maxItems int
+}
+
+// newRetentionPolicy parses the retention configuration
+func newRetentionPolicy(c config.RetentionConfig) (retentionPolicy, error) {
+	var p retentionPolicy
+
+	if c.MaxAge != "" {
+		d, err := duration.Parse(c.MaxAge)
+		if err != nil {
+			return p, fmt.Errorf("invalid max_age %q: %w", c.MaxAge, err)
+		}
+		p.maxAge = d
+	}
+
+	if c.MaxSize != "" {
+		size, err := units.FromHumanSize(c.MaxSize)
+		if err != nil {
+			return p, fmt.Errorf("invalid max_size %q: %w", c.MaxSize, err)
+		}
+		p.maxSize = size
+	}
+
+	p.maxItems = c.MaxItems
+	return p, nil
+}
+
+// isEnabled returns true if at least one limit is configured
+func (p retentionPolicy) isEnabled() bool {
+	return p.maxAge > 0 || p.maxSize > 0 || p.maxItems > 0
+}
+
+// expired returns the files that must be evicted to satisfy the policy,
+// oldest first. Files deleted at or after the given time are never evicted
+// so that files trashed by the current invocation are kept intact.
+func (p retentionPolicy) expired(files []*File, since time.Time) []*File {
+	sorted := make([]*File, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DeletedAt.Before(sorted[j].DeletedAt)
+	})
+
+	evictable := func(f *File) bool {
+		return f.DeletedAt.Before(since)
+	}
+
+	var (
+		evicted []*File
+		kept    []*File
+	)
+
+	// Evict by age
+	for _, f := range sorted {
+		if p.maxAge > 0 && evictable(f) && time.Since(f.DeletedAt) > p.maxAge {
+			evicted = append(evicted, f)
+			continue
+		}
+		kept = append(kept, f)
+	}
+
+	// Evict by item count
+	if p.maxItems > 0 {
+		var remaining []*File
+		excess := len(kept) - p.maxItems
+		for _, f := range kept {
+			if excess > 0 && evictable(f) {
+				evicted = append(evicted, f)
+				excess--
+				continue
+			}
+			remaining = append(remaining, f)
+		}
+		kept = remaining
+	}
+
+	// Evict by total size
+	if p.maxSize > 0 {
+		sizes := make(map[*File]int64, len(kept))
+		var total int64
+		for _, f := range kept {
+			size, err := f.StoredSize()
+			if err != nil {
+				slog.Debug("failed to get size", "path", f.TrashPath, "error", err)
+				continue
+			}
+			sizes[f] = size
+			total += size
+		}
+		for _, f := range kept {
+			if total <= p.maxSize {
+				break
+			}
+			if !evictable(f) {
+				continue
+			}
+			evicted = append(evicted, f)
+			total -= sizes[f]
+		}
+	}
+
+	return evicted
+}
+
+// enforceRetention permanently deletes the oldest files across all storages
+// until the retention policy is satisfied. It scans the whole trash, so Put runs
+// it once per run. It runs opportunistically, so failures are logged rather than returned.
+func (m *Manager) enforceRetention() {
+	policy, err := newRetentionPolicy(m.config.Retention)
+	if err != nil {
+		slog.Error("invalid retention policy", "error", err)
+		return
+	}
+	if !policy.isEnabled() {
+		return
+	}
+
+	files, err := m.ListAll()
+	if err != nil {
+		slog.Error("failed to list files for retention", "error", err)
+		return
+	}
+
+	for _, file := range policy.expired(files, m.startedAt) {
+		if err := m.Remove(file); err != nil {
+			slog.Error("failed to evict file from trash", "path", file.TrashPath, "error", err)
+			continue
+		}
+		slog.Info("evicted file from trash by retention policy",
+			"path", file.OriginalPath,
+			"deleted_at", file.DeletedAt)
+	}
+}
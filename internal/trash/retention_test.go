@@ -0,0 +1,133 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/config"
+)
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+
+	files := []*File{
+		{Name: "newest", DeletedAt: now.Add(-1 * day), storedSize: 10},
+		{Name: "oldest", DeletedAt: now.Add(-40 * day), storedSize: 10},
+		{Name: "middle", DeletedAt: now.Add(-10 * day), storedSize: 10},
+		{Name: "current", DeletedAt: now, storedSize: 10},
+	}
+
+	tests := []struct {
+		name   string
+		policy retentionPolicy
+		want   []string
+	}{
+		{"disabled", retentionPolicy{}, nil},
+		{"max age", retentionPolicy{maxAge: 30 * day}, []string{"oldest"}},
+		{"max items", retentionPolicy{maxItems: 2}, []string{"oldest", "middle"}},
+		{"max items keeps current run", retentionPolicy{maxItems: 1}, []string{"oldest", "middle", "newest"}},
+		{"age and items", retentionPolicy{maxAge: 30 * day, maxItems: 3}, []string{"oldest"}},
+		{"max size", retentionPolicy{maxSize: 25}, []string{"oldest", "middle"}},
+		{"max size keeps current run", retentionPolicy{maxSize: 5}, []string{"oldest", "middle", "newest"}},
+		{"max size not reached", retentionPolicy{maxSize: 40}, nil},
+		{"age and size", retentionPolicy{maxAge: 30 * day, maxSize: 25}, []string{"oldest", "middle"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.expired(files, now.Add(-time.Minute))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expired() returned %d files, want %d", len(got), len(tt.want))
+			}
+			for i, f := range got {
+				if f.Name != tt.want[i] {
+					t.Errorf("expired()[%d] = %s, want %s", i, f.Name, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.RetentionConfig
+		want    retentionPolicy
+		wantErr bool
+	}{
+		{"empty", config.RetentionConfig{}, retentionPolicy{}, false},
+		{"max size", config.RetentionConfig{MaxSize: "10GB"}, retentionPolicy{maxSize: 10_000_000_000}, false},
+		{"max age", config.RetentionConfig{MaxAge: "30d"}, retentionPolicy{maxAge: 30 * 24 * time.Hour}, false},
+		{"invalid max size", config.RetentionConfig{MaxSize: "10 apples"}, retentionPolicy{}, true},
+		{"invalid max age", config.RetentionConfig{MaxAge: "soon"}, retentionPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRetentionPolicy(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRetentionPolicy() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("newRetentionPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// retentionStorage is a Storage holding files in memory
+type retentionStorage struct {
+	Storage
+	files   []*File
+	lists   int
+	removed []string
+}
+
+func (s *retentionStorage) Info() *StorageInfo {
+	return &StorageInfo{Trashes: []string{"/trash"}}
+}
+
+func (s *retentionStorage) Put(string) error { return nil }
+
+func (s *retentionStorage) List() ([]*File, error) {
+	s.lists++
+	return s.files, nil
+}
+
+func (s *retentionStorage) Remove(file *File) error {
+	s.removed = append(s.removed, file.Name)
+	return nil
+}
+
+func TestManagerPutEnforcesRetention(t *testing.T) {
+	now := time.Now()
+	storage := &retentionStorage{files: []*File{
+		{Name: "old", TrashPath: "/trash/old", DeletedAt: now.Add(-40 * 24 * time.Hour)},
+		{Name: "new", TrashPath: "/trash/new", DeletedAt: now.Add(-time.Hour)},
+	}}
+	m := &Manager{
+		storages:  []Storage{storage},
+		config:    Config{Retention: config.RetentionConfig{MaxAge: "30d"}},
+		startedAt: now,
+	}
+
+	src := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(src, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := m.Put(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if storage.lists != 1 {
+		t.Errorf("listed the trash %d times, want once per run", storage.lists)
+	}
+	if !slices.Equal(storage.removed, []string{"old"}) {
+		t.Errorf("removed %v, want [old]", storage.removed)
+	}
+}
@@ -0,0 +1,77 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Stats summarizes the files in a storage
+type Stats struct {
+	Type StorageType
+
+	// Files is the number of files in the trash
+	Files int
+
+	// Size is the size the files take in the trash, counting files
+	// sharing their content through deduplication once per file
+	Size int64
+
+	// Dedup is nil if the storage does not deduplicate files
+	Dedup *DedupStats
+}
+
+// DedupStats reports how much space deduplication saves in a storage
+type DedupStats struct {
+	// Blobs is the number of distinct contents stored
+	Blobs int
+
+	// Refs is the number of trashed files pointing at a blob
+	Refs int
+
+	// Stored is the size of the blobs, each stored once
+	Stored int64
+
+	// Saved is the size that would be taken by the other copies of the blobs
+	Saved int64
+}
+
+// Deduplicator is implemented by storages that store identical contents once
+type Deduplicator interface {
+	// DedupStats reports how much space deduplication saves
+	DedupStats() (DedupStats, error)
+}
+
+// Stats summarizes the files of every storage
+func (m *Manager) Stats() ([]Stats, error) {
+	var (
+		stats []Stats
+		errs  []error
+	)
+	for _, storage := range m.storages {
+		files, err := storage.List()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list files from %s storage: %w", storage.Info().Type, err))
+			continue
+		}
+		st := Stats{Type: storage.Info().Type, Files: len(files)}
+		for _, file := range files {
+			size, err := file.StoredSize()
+			if err != nil {
+				slog.Warn("failed to get size", "path", file.TrashPath, "error", err)
+				continue
+			}
+			st.Size += size
+		}
+		if d, ok := storage.(Deduplicator); ok {
+			dedup, err := d.DedupStats()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to get deduplication stats of %s storage: %w", storage.Info().Type, err))
+			} else {
+				st.Dedup = &dedup
+			}
+		}
+		stats = append(stats, st)
+	}
+	return stats, errors.Join(errs...)
+}
@@ -2,11 +2,15 @@
 package trash
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
+
+	gomifs "github.com/babarot/gomi/internal/utils/fs"
 )
 
 // StorageType represents the type of trash storage
@@ -72,21 +76,49 @@ type File struct {
 	// DeletedAt is when the file was moved to trash
 	DeletedAt time.Time
 
-	// Size is the size of the file in bytes
+	// Size is the size of the file in bytes.
+	// For a compressed file, it is the size before compression.
 	Size int64
 
+	// Compression is how the file is compressed in the trash (e.g., "zstd", "tar+zstd").
+	// It is empty if the file is stored as is.
+	Compression string
+
 	// IsDir indicates if this is a directory
 	IsDir bool
 
 	// FileMode is the original mode of the file
 	FileMode fs.FileMode
 
+	// Checksum is the checksum of the file as stored in trash (e.g., "sha256:..."),
+	// recorded when it was trashed. It is empty if checksums were disabled.
+	Checksum string
+
+	// Metadata is the ownership, mode, times and extended attributes of the file
+	// recorded when it was moved to trash, to be reapplied on restore.
+	// It is nil for files trashed without it.
+	Metadata *gomifs.Metadata
+
+	// RunID identifies the invocation that moved this file to trash
+	RunID string
+
 	// MountRoot is the root path of the mount point containing this trash
 	// This is used to resolve relative paths in .trashinfo files
 	MountRoot string
 
 	// storage is a reference to the Storage implementation that manages this file
 	storage Storage
+
+	// storedSize is the size the file takes in the trash if known by the storage
+	// (e.g., from the XDG directorysizes cache), so that it doesn't have to be calculated
+	storedSize int64
+}
+
+// ID returns a short identifier of the file that stays stable
+// as long as the file remains at the same location in the trash
+func (f *File) ID() string {
+	sum := sha256.Sum256([]byte(f.TrashPath))
+	return hex.EncodeToString(sum[:])[:12]
 }
 
 func (f *File) GetName() string {
@@ -101,6 +133,10 @@ func (f *File) GetDeletedAt() time.Time {
 	return f.DeletedAt
 }
 
+func (f *File) GetSize() (int64, error) {
+	return f.StoredSize()
+}
+
 // Exists checks if the file still exists in the trash
 func (f *File) Exists() bool {
 	_, err := os.Stat(f.TrashPath)
@@ -178,19 +214,36 @@ type Storage interface {
 	Info() *StorageInfo
 }
 
+// Importer is implemented by storages that can take over a file stored by another storage
+type Importer interface {
+	// Import moves the file stored at src into this storage, keeping the original path,
+	// deletion date, run ID and compression of the file. src no longer exists when it succeeds.
+	// An interrupted import is resumed by importing the same src again.
+	Import(src string, file *File) error
+}
+
 // StorageConstructor is a function type for creating new Storage instances
 type StorageConstructor func(Config) (Storage, error)
 
-// IsExistLegacy checks if legacy storage exists
-func IsExistLegacy() (bool, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return false, fmt.Errorf("failed to get home directory: %w", err)
+// IsExistLegacy checks if legacy storage exists in gomiDir (~/.gomi if empty).
+// A legacy storage is in use as long as it has a history, which is retired
+// once every file has been migrated to another storage.
+func IsExistLegacy(gomiDir string) (bool, error) {
+	if gomiDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		gomiDir = filepath.Join(home, ".gomi")
 	}
 
-	legacyPath := filepath.Join(home, ".gomi")
-	if fi, err := os.Stat(legacyPath); err == nil && fi.IsDir() {
-		return true, nil
+	if fi, err := os.Stat(gomiDir); err != nil || !fi.IsDir() {
+		return false, nil
+	}
+	for _, name := range []string{"history.json", "history.json.backup"} {
+		if _, err := os.Stat(filepath.Join(gomiDir, name)); err == nil {
+			return true, nil
+		}
 	}
 
 	return false, nil
@@ -0,0 +1,166 @@
+package xdg
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/google/uuid"
+)
+
+// directorySizesName is the cache of directory sizes defined by the XDG trash spec 1.0
+const directorySizesName = "directorysizes"
+
+// lockName is the lock file in the trash root guarding updates of the directorysizes cache
+const lockName = ".gomi.lock"
+
+// dirSize is an entry of the directorysizes cache
+type dirSize struct {
+	// Size is the size of the directory in bytes
+	Size int64
+
+	// Mtime is the modification time of the .trashinfo file of the directory
+	// in seconds since the epoch, which tells whether the entry is stale
+	Mtime int64
+}
+
+// dirSizes is the directorysizes cache of a trash location, keyed by
+// the name of the directory in the files directory
+type dirSizes map[string]dirSize
+
+// loadDirSizes reads the directorysizes cache of the trash root.
+// A missing cache is empty, and malformed lines are ignored.
+func loadDirSizes(root string) (dirSizes, error) {
+	sizes := dirSizes{}
+
+	f, err := os.Open(filepath.Join(root, directorySizesName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sizes, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Each line is "[size] [mtime] [percent-encoded directory name]"
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		mtime, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		name, err := url.PathUnescape(fields[2])
+		if err != nil {
+			continue
+		}
+		sizes[name] = dirSize{Size: size, Mtime: mtime}
+	}
+	return sizes, scanner.Err()
+}
+
+// save writes the cache to the trash root.
+// It is written to a temporary file first and renamed over the cache,
+// so that other clients never read a partial file.
+func (d dirSizes) save(root string) error {
+	content := new(strings.Builder)
+	for name, entry := range d {
+		fmt.Fprintf(content, "%d %d %s\n", entry.Size, entry.Mtime, encodeTrashPath(name))
+	}
+
+	tmpPath := filepath.Join(root, fmt.Sprintf(".%s.%s.tmp", directorySizesName, uuid.New().String()))
+	if err := os.WriteFile(tmpPath, []byte(content.String()), 0600); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", directorySizesName, err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(root, directorySizesName)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", directorySizesName, err)
+	}
+	return nil
+}
+
+// lookup returns the cached size of the directory if the entry
+// is not older than its .trashinfo file
+func (d dirSizes) lookup(name string, infoPath string) (int64, bool) {
+	entry, ok := d[name]
+	if !ok {
+		return 0, false
+	}
+	fi, err := os.Stat(infoPath)
+	if err != nil || fi.ModTime().Unix() != entry.Mtime {
+		return 0, false
+	}
+	return entry.Size, true
+}
+
+// set records the size of the directory along with the mtime of its .trashinfo file
+func (d dirSizes) set(name string, size int64, infoPath string) error {
+	fi, err := os.Stat(infoPath)
+	if err != nil {
+		return err
+	}
+	d[name] = dirSize{Size: size, Mtime: fi.ModTime().Unix()}
+	return nil
+}
+
+// updateDirSizes applies change to the directorysizes cache of the trash root,
+// and saves it if change reports that the cache was modified.
+// The trash root is locked meanwhile, so that updates of other processes are not lost.
+func updateDirSizes(root string, change func(dirSizes) bool) error {
+	unlock, err := fs.Lock(filepath.Join(root, lockName))
+	if err != nil {
+		return fmt.Errorf("failed to lock trash: %w", err)
+	}
+	defer unlock()
+
+	sizes, err := loadDirSizes(root)
+	if err != nil {
+		return err
+	}
+	if !change(sizes) {
+		return nil
+	}
+	return sizes.save(root)
+}
+
+// updateDirSize adds the directory to the cache of the trash location
+func (loc *trashLocation) updateDirSize(name string, size int64) {
+	err := updateDirSizes(loc.root, func(sizes dirSizes) bool {
+		if err := sizes.set(name, size, filepath.Join(loc.infoDir, name+".trashinfo")); err != nil {
+			slog.Warn("failed to update directory sizes", "root", loc.root, "error", err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		slog.Warn("failed to save directory sizes", "root", loc.root, "error", err)
+	}
+}
+
+// removeDirSize removes the entry of the file from the cache of the trash root, if any
+func removeDirSize(root, name string) {
+	err := updateDirSizes(root, func(sizes dirSizes) bool {
+		if _, ok := sizes[name]; !ok {
+			return false
+		}
+		delete(sizes, name)
+		return true
+	})
+	if err != nil {
+		slog.Warn("failed to save directory sizes", "root", root, "error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDirSizes(t *testing.T) {
+	root := t.TempDir()
+	infoDir := filepath.Join(root, "info")
+	if err := os.Mkdir(infoDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"dir", "dir with space", "100%+done"}
+	sizes := dirSizes{}
+	for i, name := range names {
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		if err := os.WriteFile(infoPath, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := sizes.set(name, int64(i+1)*1024, infoPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sizes.save(root); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadDirSizes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		size, ok := loaded.lookup(name, filepath.Join(infoDir, name+".trashinfo"))
+		if !ok || size != int64(i+1)*1024 {
+			t.Errorf("lookup(%q) = %d, %v, want %d, true", name, size, ok, int64(i+1)*1024)
+		}
+	}
+
+	// An entry older than its .trashinfo is stale
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(infoDir, "dir.trashinfo"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.lookup("dir", filepath.Join(infoDir, "dir.trashinfo")); ok {
+		t.Error("lookup returned a stale entry")
+	}
+
+	removeDirSize(root, "dir with space")
+	loaded, err = loadDirSizes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded["dir with space"]; ok || len(loaded) != 2 {
+		t.Errorf("entries after removal = %v", loaded)
+	}
+}
+
+func TestLoadDirSizesMissing(t *testing.T) {
+	sizes, err := loadDirSizes(t.TempDir())
+	if err != nil || len(sizes) != 0 {
+		t.Errorf("loadDirSizes() = %v, %v, want empty", sizes, err)
+	}
+}
@@ -0,0 +1,215 @@
+package xdg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+)
+
+// Check reports orphans, unparsable .trashinfo files, leftover temporary
+// files and unsafe permissions of external trashes
+func (s *Storage) Check() ([]trash.Problem, error) {
+	problems, err := s.checkLocation(s.homeTrash)
+	if err != nil {
+		return nil, err
+	}
+	for _, loc := range s.externalTrashes {
+		found, err := s.checkLocation(loc)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// checkLocation reports the problems of the trash location. Files being moved in are
+// not reported: moves into the location are waited for, and paths of pending journal
+// entries are skipped. Each fix checks again that the problem is still there.
+func (s *Storage) checkLocation(loc *trashLocation) ([]trash.Problem, error) {
+	var problems []trash.Problem
+
+	unlock, err := loc.lockCheck()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	journaled, err := s.journal.Paths(trash.StorageTypeXDG.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if !loc.isHome && runtime.GOOS != "windows" {
+		for _, dir := range []string{loc.root, loc.filesDir, loc.infoDir} {
+			fi, err := os.Stat(dir)
+			if err != nil || fi.Mode().Perm() == 0700 {
+				continue
+			}
+			problems = append(problems, trash.NewProblem(trash.ProblemPermission, dir,
+				fmt.Sprintf("mode is %#o, expected 0700", fi.Mode().Perm()),
+				func(trash.OrphanAction) error {
+					return os.Chmod(dir, 0700)
+				}))
+		}
+	}
+
+	entries, err := os.ReadDir(loc.filesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(loc.filesDir, name)
+		infoPath := filepath.Join(loc.infoDir, name+".trashinfo")
+		if journaled[path] || journaled[infoPath] {
+			continue
+		}
+		// An orphan is one whose .trashinfo is still missing when it is fixed
+		orphan := func() bool {
+			_, err := os.Lstat(infoPath)
+			return os.IsNotExist(err)
+		}
+		_, err := loadTrashInfo(infoPath)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, fs.ErrNotExist) && trash.IsTempName(name):
+			// Trashed files may look like temporary files, but have a .trashinfo
+			problems = append(problems, trash.NewProblem(trash.ProblemStaleFile, path,
+				"leftover temporary file",
+				s.lockedFix(loc, path, orphan, func(trash.OrphanAction) error {
+					return os.RemoveAll(path)
+				})))
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, trash.NewProblem(trash.ProblemOrphanFile, path,
+				"no .trashinfo file",
+				s.lockedFix(loc, path, orphan, func(action trash.OrphanAction) error {
+					return s.fixOrphan(loc, name, action)
+				})))
+		default:
+			invalid := func() bool {
+				_, err := loadTrashInfo(infoPath)
+				return err != nil && !errors.Is(err, fs.ErrNotExist)
+			}
+			problems = append(problems, trash.NewProblem(trash.ProblemInvalidInfo, infoPath,
+				err.Error(),
+				s.lockedFix(loc, infoPath, invalid, func(action trash.OrphanAction) error {
+					if err := os.Remove(infoPath); err != nil {
+						return err
+					}
+					return s.fixOrphan(loc, name, action)
+				})))
+		}
+	}
+
+	infos, err := os.ReadDir(loc.infoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read info directory: %w", err)
+	}
+	for _, info := range infos {
+		name, ok := strings.CutSuffix(info.Name(), ".trashinfo")
+		if !ok {
+			continue
+		}
+		path := filepath.Join(loc.filesDir, name)
+		infoPath := filepath.Join(loc.infoDir, info.Name())
+		if journaled[path] || journaled[infoPath] {
+			continue
+		}
+		missing := func() bool {
+			_, err := os.Lstat(path)
+			return os.IsNotExist(err)
+		}
+		if !missing() {
+			continue
+		}
+		problems = append(problems, trash.NewProblem(trash.ProblemOrphanInfo, infoPath,
+			"the file is missing from the trash",
+			s.lockedFix(loc, infoPath, missing, func(trash.OrphanAction) error {
+				return os.Remove(infoPath)
+			})))
+	}
+
+	tmps, err := filepath.Glob(filepath.Join(loc.root, "."+directorySizesName+".*.tmp"))
+	if err != nil {
+		return nil, err
+	}
+	for _, tmp := range tmps {
+		problems = append(problems, trash.NewProblem(trash.ProblemStaleFile, tmp,
+			"leftover temporary file",
+			func(trash.OrphanAction) error {
+				return os.Remove(tmp)
+			}))
+	}
+
+	// Staging directories are left if gomi is killed while importing an archive
+	stages, err := filepath.Glob(filepath.Join(loc.root, stagingPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range stages {
+		problems = append(problems, trash.NewProblem(trash.ProblemStaleFile, stage,
+			"leftover staging directory of an import",
+			func(trash.OrphanAction) error {
+				return os.RemoveAll(stage)
+			}))
+	}
+
+	return problems, nil
+}
+
+// lockedFix returns fix to be applied with the trash location locked, and only if
+// the problem at path is still there: no journal entry is moving it, and found
+// reports it again. Otherwise, a move into the trash has completed since the check.
+func (s *Storage) lockedFix(loc *trashLocation, path string, found func() bool, fix func(trash.OrphanAction) error) func(trash.OrphanAction) error {
+	return func(action trash.OrphanAction) error {
+		unlock, err := loc.lockCheck()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		journaled, err := s.journal.Paths(trash.StorageTypeXDG.String())
+		if err != nil {
+			return err
+		}
+		if journaled[path] || !found() {
+			slog.Debug("problem is gone", "path", path)
+			return nil
+		}
+		return fix(action)
+	}
+}
+
+// fixOrphan adopts the file with a synthesized .trashinfo or deletes it
+func (s *Storage) fixOrphan(loc *trashLocation, name string, action trash.OrphanAction) error {
+	path := filepath.Join(loc.filesDir, name)
+	if action == trash.OrphanDelete {
+		return os.RemoveAll(path)
+	}
+
+	// The original location is unknown, so the file is restored
+	// to the home directory or to the top directory of the mount point
+	dir := loc.mountRoot
+	if loc.isHome {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dir = home
+	}
+	info := &TrashInfo{
+		Path:         filepath.Join(dir, name),
+		MountRoot:    loc.mountRoot,
+		DeletionDate: time.Now(),
+		RunID:        s.config.RunID,
+	}
+	return info.Save(filepath.Join(loc.infoDir, name+".trashinfo"))
+}
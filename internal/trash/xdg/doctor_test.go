@@ -0,0 +1,143 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/journal"
+)
+
+func TestCheckTempFiles(t *testing.T) {
+	s := newTestStorage(t)
+	loc := s.homeTrash
+
+	write := func(name string) string {
+		path := filepath.Join(loc.filesDir, name)
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// Trashed files named like temporary files have a .trashinfo
+	for _, name := range []string{".session.tmp", ".a.txt.0b5c2c4e-8f1a-4a55-9a3e-2f4d1c6b7a80.tmp"} {
+		write(name)
+		info := &TrashInfo{Path: "/home/me/" + name, DeletionDate: time.Now()}
+		if err := info.Save(filepath.Join(loc.infoDir, name+".trashinfo")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leftover := write(".b.txt.0b5c2c4e-8f1a-4a55-9a3e-2f4d1c6b7a80.tmp")
+	orphan := write(".c.tmp")
+	stage, err := s.Stage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]trash.ProblemKind{}
+	for _, p := range problems {
+		got[p.Path] = p.Kind
+	}
+	want := map[string]trash.ProblemKind{
+		leftover: trash.ProblemStaleFile,
+		orphan:   trash.ProblemOrphanFile,
+		stage:    trash.ProblemStaleFile,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Check() found %v, want %v", got, want)
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("Check() reported %q as %q, want %q", path, got[path], kind)
+		}
+	}
+}
+
+func TestCheckInFlight(t *testing.T) {
+	s := newTestStorage(t)
+	loc := s.homeTrash
+	j, err := journal.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.journal = j
+
+	// A put reserved its .trashinfo and is moving the file in
+	unlock, err := loc.lockMoves()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &TrashInfo{Path: "/home/me/a.txt", DeletionDate: time.Now()}
+	if err := info.Save(filepath.Join(loc.infoDir, "a.txt.trashinfo")); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan []trash.Problem)
+	go func() {
+		problems, err := s.Check()
+		if err != nil {
+			t.Error(err)
+		}
+		done <- problems
+	}()
+	select {
+	case problems := <-done:
+		t.Fatalf("Check() returned %v while a file is moved into the trash", problems)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := os.WriteFile(filepath.Join(loc.filesDir, "a.txt"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if problems := <-done; len(problems) > 0 {
+		t.Errorf("Check() found %v after the put completed", problems)
+	}
+
+	// A restore of a running process is moving the file out
+	entry := &journal.Entry{
+		Op:      journal.OpRestore,
+		Storage: trash.StorageTypeXDG.String(),
+		Src:     filepath.Join(loc.filesDir, "a.txt"),
+		Dst:     journal.StagingPath("/home/me/a.txt"),
+		Target:  "/home/me/a.txt",
+		Info:    filepath.Join(loc.infoDir, "a.txt.trashinfo"),
+	}
+	if err := j.Begin(entry); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Commit(entry)
+	if err := os.Remove(entry.Src); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("Check() found %v while a file is restored", problems)
+	}
+	j.Commit(entry)
+
+	// A problem gone since the check is not fixed
+	problems, err = s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Kind != trash.ProblemOrphanInfo {
+		t.Fatalf("Check() found %v, want the .trashinfo without its file", problems)
+	}
+	if err := os.WriteFile(entry.Src, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := problems[0].Fix(trash.OrphanAdopt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(entry.Info); err != nil {
+		t.Errorf("the .trashinfo of a file moved in since the check is removed: %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package xdg
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/compress"
+	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/google/uuid"
+)
+
+// Import moves a file stored by another trash into home trash.
+//
+// The .trashinfo is written first and records src, then the file is renamed into
+// the files directory, or copied to a temporary file and renamed if src is on
+// another device. The size of the moved file is verified before src is removed,
+// and src is no longer recorded once the import is complete.
+// If an import is interrupted, importing the same file from src again either finds
+// the completed copy or discards the partial one and starts over.
+//
+// A compressed file is decompressed in a staging directory first, as other clients
+// of the trash would restore the compressed bytes.
+func (s *Storage) Import(src string, file *trash.File) error {
+	loc := s.homeTrash
+	unlock, err := loc.lockMoves()
+	if err != nil {
+		return trash.NewStorageError("import", src, err)
+	}
+	defer unlock()
+
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return trash.NewStorageError("import", src, err)
+	}
+	size := file.Size
+	if !file.IsCompressed() {
+		if size, err = fs.DirSize(src); err != nil {
+			return trash.NewStorageError("import", src, fmt.Errorf("failed to get size: %w", err))
+		}
+	}
+
+	// Staged files are renamed into the trash, so their import is never interrupted
+	// halfway, and their temporary path is not recorded as the source
+	staged := s.isStaged(src)
+	if !staged {
+		done, err := s.resumeImport(loc, src, file, size)
+		if err != nil {
+			return trash.NewStorageError("import", src, err)
+		}
+		if done {
+			slog.Debug("already imported", "path", src)
+			return nil
+		}
+	}
+
+	path := src
+	checksum := file.Checksum
+	if file.IsCompressed() {
+		dir, err := s.Stage()
+		if err != nil {
+			return trash.NewStorageError("import", src, err)
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, filepath.Base(src))
+		if err := decompress(src, path, file.Compression); err != nil {
+			return trash.NewStorageError("import", src, err)
+		}
+		// The checksum is the one of the compressed file
+		checksum = s.config.Sum(path)
+		if fi, err = os.Lstat(path); err != nil {
+			return trash.NewStorageError("import", src, err)
+		}
+	}
+
+	info := &TrashInfo{
+		Path:         file.OriginalPath,
+		DeletionDate: file.DeletedAt,
+		RunID:        file.RunID,
+		Metadata:     file.Metadata,
+		Checksum:     checksum,
+	}
+	if !staged {
+		info.ImportedFrom = src
+	}
+	name, infoPath, err := loc.reserveName(filepath.Base(file.OriginalPath), info)
+	if err != nil {
+		return trash.NewStorageError("import", src, fmt.Errorf("failed to save trash info: %w", err))
+	}
+
+	dst := filepath.Join(loc.filesDir, name)
+	if err := moveVerified(path, dst, size); err != nil {
+		if _, statErr := os.Lstat(dst); statErr == nil {
+			// The file is in trash, and importing src again removes what is left of it
+			s.imported[src] = append(s.imported[src], name)
+		} else {
+			os.Remove(infoPath)
+		}
+		return trash.NewStorageError("import", src, err)
+	}
+	if path != src {
+		// The decompressed copy is in trash, so the compressed file can be removed
+		if err := os.RemoveAll(src); err != nil {
+			return trash.NewStorageError("import", src, fmt.Errorf("imported but failed to remove original: %w", err))
+		}
+	}
+
+	// Another file may be trashed at src later, which must not be taken for this one
+	if info.ImportedFrom != "" {
+		info.ImportedFrom = ""
+		if err := info.Replace(infoPath); err != nil {
+			slog.Warn("failed to clear the import source", "path", infoPath, "error", err)
+		}
+	}
+
+	if fi.IsDir() {
+		loc.updateDirSize(name, size)
+	}
+	return nil
+}
+
+// decompress extracts the file at src compressed with method to dst
+func decompress(src, dst, method string) error {
+	m, err := compress.ParseMethod(method)
+	if err != nil {
+		return err
+	}
+	if err := compress.Decompress(src, dst, m); err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	return nil
+}
+
+// stagingPrefix is the prefix of the directories in home trash where files are
+// prepared to be imported, such as the files of an archive being extracted
+const stagingPrefix = ".gomi-staging-"
+
+// Stage creates a directory in home trash to prepare files to import in, so that
+// importing them is a rename on the same device. The caller removes it when done.
+func (s *Storage) Stage() (string, error) {
+	return os.MkdirTemp(s.homeTrash.root, stagingPrefix)
+}
+
+// isStaged reports whether path is in a staging directory of home trash
+func (s *Storage) isStaged(path string) bool {
+	rel, err := filepath.Rel(s.homeTrash.root, path)
+	return err == nil && filepath.IsLocal(rel) && strings.HasPrefix(rel, stagingPrefix)
+}
+
+// resumeImport looks for an interrupted import of the same file from src, which is
+// recorded with src, the original path and the deletion date of the file.
+// It returns true if the file was copied but not removed from src yet, and
+// otherwise removes the .trashinfo of the interrupted import.
+func (s *Storage) resumeImport(loc *trashLocation, src string, file *trash.File, size int64) (bool, error) {
+	if s.imported == nil {
+		s.imported = make(map[string][]string)
+		infos, err := os.ReadDir(loc.infoDir)
+		if err != nil {
+			return false, fmt.Errorf("failed to read info directory: %w", err)
+		}
+		for _, entry := range infos {
+			name, ok := strings.CutSuffix(entry.Name(), ".trashinfo")
+			if !ok {
+				continue
+			}
+			info, err := loadTrashInfo(filepath.Join(loc.infoDir, entry.Name()))
+			if err != nil || info.ImportedFrom == "" {
+				continue
+			}
+			s.imported[info.ImportedFrom] = append(s.imported[info.ImportedFrom], name)
+		}
+	}
+
+	for i, name := range s.imported[src] {
+		infoPath := filepath.Join(loc.infoDir, name+".trashinfo")
+		info, err := loadTrashInfo(infoPath)
+		if err != nil || !sameImport(info, file) {
+			continue
+		}
+		dst := filepath.Join(loc.filesDir, name)
+		if _, err := os.Lstat(dst); err == nil {
+			// The file may have been copied from another device but the interruption
+			// happened while removing it from there. It is only removed if the copy is complete.
+			if got, err := fs.DirSize(dst); err != nil || got != size {
+				continue
+			}
+			if err := os.RemoveAll(src); err != nil {
+				return false, err
+			}
+			s.imported[src] = slices.Delete(s.imported[src], i, i+1)
+			info.ImportedFrom = ""
+			if err := info.Replace(infoPath); err != nil {
+				slog.Warn("failed to clear the import source", "path", infoPath, "error", err)
+			}
+			return true, nil
+		}
+
+		slog.Debug("discard interrupted import", "path", src, "name", name)
+		s.imported[src] = slices.Delete(s.imported[src], i, i+1)
+		if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+// sameImport reports whether the .trashinfo records the import of the file.
+// Deletion dates are compared to the second, as they are saved so.
+func sameImport(info *TrashInfo, file *trash.File) bool {
+	return info.Path == file.OriginalPath &&
+		info.DeletionDate.Truncate(time.Second).Equal(file.DeletedAt.Truncate(time.Second))
+}
+
+// moveVerified moves src to dst and checks that dst has the expected size.
+// When src is on another device, it is copied next to dst first,
+// so that dst only appears once it is complete.
+func moveVerified(src, dst string, size int64) error {
+	if err := os.Rename(src, dst); err == nil {
+		if got, err := fs.DirSize(dst); err != nil || got != size {
+			// Put it back, as the file in the trash cannot be trusted
+			if err := os.Rename(dst, src); err != nil {
+				return fmt.Errorf("failed to verify %s and to move it back: %w", dst, err)
+			}
+			return fmt.Errorf("failed to verify %s: size is %d, expected %d", dst, got, size)
+		}
+		return nil
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.%s.tmp", filepath.Base(dst), uuid.New().String()))
+	if err := fs.Copy(src, tmpPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	if got, err := fs.DirSize(tmpPath); err != nil || got != size {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to verify copy of %s: size is %d, expected %d", src, got, size)
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("imported but failed to remove original: %w", err)
+	}
+	return nil
+}
+
+// ImportRoot imports every file of a trash directory not managed by gomi, such as
+// one used by trash-cli or left on an old drive, into home trash. path is either
+// a trash directory containing "files" and "info", or the top directory of a mount
+// point containing $topdir/.Trash/$uid or $topdir/.Trash-$uid.
+// Names colliding with files already in trash are given a "_N" suffix.
+// Files without a valid .trashinfo are left as they are.
+// report is called with the result of importing each file.
+func (s *Storage) ImportRoot(path string, report func(file *trash.File, err error)) error {
+	loc, err := findTrashRoot(path)
+	if err != nil {
+		return err
+	}
+	if loc.root == s.homeTrash.root || s.isManaged(loc.root) {
+		return fmt.Errorf("%s is already managed by gomi", loc.root)
+	}
+
+	files, err := s.listForeignLocation(loc)
+	if err != nil {
+		return err
+	}
+	// The cache of the trash is only updated if it has the entry of an imported directory
+	sizes, err := loadDirSizes(loc.root)
+	if err != nil {
+		slog.Warn("failed to load directory sizes", "root", loc.root, "error", err)
+		sizes = dirSizes{}
+	}
+	for _, file := range files {
+		err := s.Import(file.TrashPath, file)
+		if err == nil {
+			name := filepath.Base(file.TrashPath)
+			if err = os.Remove(filepath.Join(loc.infoDir, name+".trashinfo")); err != nil {
+				err = fmt.Errorf("imported but failed to remove trash info: %w", err)
+			}
+			if _, ok := sizes[name]; ok {
+				removeDirSize(loc.root, name)
+			}
+		}
+		report(file, err)
+	}
+	return nil
+}
+
+// isManaged reports whether root is one of the external trashes of the storage
+func (s *Storage) isManaged(root string) bool {
+	for _, ext := range s.externalTrashes {
+		if ext.root == root {
+			return true
+		}
+	}
+	return false
+}
+
+// findTrashRoot returns the trash directory at path, or the one of the
+// current user in path if it is the top directory of a mount point
+func findTrashRoot(path string) (*trashLocation, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+	candidates := []string{
+		abs,
+		filepath.Join(abs, ".Trash", uid),
+		filepath.Join(abs, ".Trash-"+uid),
+	}
+	for _, root := range candidates {
+		if !isTrashDir(root) {
+			continue
+		}
+		// Paths in .trashinfo files of an external trash are relative to its top directory
+		topdir := filepath.Dir(root)
+		if filepath.Base(topdir) == ".Trash" {
+			topdir = filepath.Dir(topdir)
+		}
+		return &trashLocation{
+			root:      root,
+			filesDir:  filepath.Join(root, "files"),
+			infoDir:   filepath.Join(root, "info"),
+			mountRoot: topdir,
+		}, nil
+	}
+	return nil, fmt.Errorf("no trash directory found in %s", abs)
+}
+
+// isTrashDir reports whether the directory has the "files" and "info" directories of a trash
+func isTrashDir(root string) bool {
+	for _, dir := range []string{"files", "info"} {
+		fi, err := os.Stat(filepath.Join(root, dir))
+		if err != nil || !fi.IsDir() {
+			return false
+		}
+	}
+	return true
+}
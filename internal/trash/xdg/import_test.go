@@ -0,0 +1,307 @@
+package xdg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/compress"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "Trash")
+	loc := &trashLocation{
+		root:     root,
+		filesDir: filepath.Join(root, "files"),
+		infoDir:  filepath.Join(root, "info"),
+		isHome:   true,
+	}
+	for _, dir := range []string{loc.filesDir, loc.infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Storage{homeTrash: loc}
+}
+
+func TestImport(t *testing.T) {
+	s := newTestStorage(t)
+	src := filepath.Join(t.TempDir(), "a.txt.id")
+	if err := os.WriteFile(src, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	file := &trash.File{OriginalPath: "/home/me/a.txt", DeletedAt: deletedAt, RunID: "run1"}
+
+	// An interrupted import left a .trashinfo without the file
+	stale := &TrashInfo{Path: file.OriginalPath, DeletionDate: deletedAt, ImportedFrom: src}
+	if err := stale.Save(filepath.Join(s.homeTrash.infoDir, "a.txt.trashinfo")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Import(src, file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after import", src)
+	}
+
+	files, err := s.listLocation(s.homeTrash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files in trash, want 1", len(files))
+	}
+	got := files[0]
+	if got.OriginalPath != file.OriginalPath || !got.DeletedAt.Equal(deletedAt) || got.RunID != "run1" {
+		t.Errorf("imported file = %+v", got)
+	}
+
+	infoPath := filepath.Join(s.homeTrash.infoDir, "a.txt.trashinfo")
+	info, err := loadTrashInfo(infoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ImportedFrom != "" {
+		t.Errorf("ImportedFrom = %q is left after the import completed", info.ImportedFrom)
+	}
+
+	// The file has been copied, but removing src was interrupted
+	info.ImportedFrom = src
+	if err := info.Replace(infoPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.imported = nil
+	if err := s.Import(src, file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after resuming import", src)
+	}
+	if files, _ := s.listLocation(s.homeTrash); len(files) != 1 {
+		t.Errorf("got %d files in trash after resuming import, want 1", len(files))
+	}
+
+	// Another file trashed at src later is imported as well
+	if err := os.WriteFile(src, []byte("new content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	newer := &trash.File{OriginalPath: "/home/me/a.txt", DeletedAt: deletedAt.Add(time.Hour)}
+	if err := s.Import(src, newer); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := s.listLocation(s.homeTrash); len(files) != 2 {
+		t.Errorf("got %d files in trash after importing another file, want 2", len(files))
+	}
+}
+
+func TestImportStaged(t *testing.T) {
+	s := newTestStorage(t)
+	dir, err := s.Stage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(dir) != s.homeTrash.root {
+		t.Errorf("staging directory %s is not in %s", dir, s.homeTrash.root)
+	}
+	src := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file := &trash.File{OriginalPath: "/home/me/a.txt", DeletedAt: time.Now()}
+	if err := s.Import(src, file); err != nil {
+		t.Fatal(err)
+	}
+
+	// The temporary path of a staged file is not recorded as its source
+	info, err := loadTrashInfo(filepath.Join(s.homeTrash.infoDir, "a.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ImportedFrom != "" {
+		t.Errorf("ImportedFrom = %q, want none for a staged file", info.ImportedFrom)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after import", src)
+	}
+}
+
+func TestImportCompressed(t *testing.T) {
+	s := newTestStorage(t)
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(plain, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "a.txt.id")
+	m, err := compress.Compress(plain, src, compress.Zstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &trash.File{
+		OriginalPath: "/home/me/a.txt",
+		DeletedAt:    time.Now(),
+		Size:         5,
+		Compression:  m.String(),
+		Checksum:     "sha256:of-the-compressed-file",
+	}
+	if err := s.Import(src, file); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other clients of the trash restore the file as it is in the files directory
+	got, err := os.ReadFile(filepath.Join(s.homeTrash.filesDir, "a.txt"))
+	if err != nil || string(got) != "hello" {
+		t.Errorf("got %q (%v) in trash, want %q", got, err, "hello")
+	}
+	info, err := loadTrashInfo(filepath.Join(s.homeTrash.infoDir, "a.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Compression != "" || info.Checksum != "" || info.ImportedFrom != "" {
+		t.Errorf("got trash info %+v of a decompressed file", info)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after import", src)
+	}
+	if stages, _ := filepath.Glob(filepath.Join(s.homeTrash.root, stagingPrefix+"*")); len(stages) > 0 {
+		t.Errorf("staging directories %v are left", stages)
+	}
+}
+
+func TestImportRoot(t *testing.T) {
+	s := newTestStorage(t)
+	if err := os.WriteFile(filepath.Join(s.homeTrash.filesDir, "a"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	existing := &TrashInfo{Path: "/home/me/a", DeletionDate: time.Now()}
+	if err := existing.Save(filepath.Join(s.homeTrash.infoDir, "a.trashinfo")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A trash-cli trash on another drive, with a path relative to the top directory
+	topdir := t.TempDir()
+	other := filepath.Join(topdir, fmt.Sprintf(".Trash-%d", os.Getuid()))
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(other, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(other, "files", "a"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	content := "[Trash Info]\nPath=docs/a\nDeletionDate=2020-05-06T07:08:09\n"
+	if err := os.WriteFile(filepath.Join(other, "info", "a.trashinfo"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var imported []*trash.File
+	err := s.ImportRoot(topdir, func(file *trash.File, err error) {
+		if err != nil {
+			t.Errorf("failed to import %s: %v", file.OriginalPath, err)
+		}
+		imported = append(imported, file)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("imported %d files, want 1", len(imported))
+	}
+
+	info, err := loadTrashInfo(filepath.Join(s.homeTrash.infoDir, "a_1.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(topdir, "docs", "a"); info.Path != want {
+		t.Errorf("Path = %q, want %q", info.Path, want)
+	}
+	if want := time.Date(2020, 5, 6, 7, 8, 9, 0, time.Local); !info.DeletionDate.Equal(want) {
+		t.Errorf("DeletionDate = %v, want %v", info.DeletionDate, want)
+	}
+	if _, err := os.Lstat(filepath.Join(other, "info", "a.trashinfo")); !os.IsNotExist(err) {
+		t.Error("trash info is left in the imported trash")
+	}
+
+	// A file trashed into the other trash again under the same name is imported too
+	if err := os.WriteFile(filepath.Join(other, "files", "a"), []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	content = "[Trash Info]\nPath=docs/a\nDeletionDate=2021-05-06T07:08:09\n"
+	if err := os.WriteFile(filepath.Join(other, "info", "a.trashinfo"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err = s.ImportRoot(topdir, func(file *trash.File, err error) {
+		if err != nil {
+			t.Errorf("failed to import %s: %v", file.OriginalPath, err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, name := range []string{"a_1", "a_2"} {
+		data, err := os.ReadFile(filepath.Join(s.homeTrash.filesDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[name] = string(data)
+	}
+	if got["a_1"] != "old" || got["a_2"] != "new" {
+		t.Errorf("imported files = %v, want a_1 = old and a_2 = new", got)
+	}
+}
+
+func TestImportRootReadOnly(t *testing.T) {
+	s := newTestStorage(t)
+
+	other := filepath.Join(t.TempDir(), "Trash")
+	if err := os.MkdirAll(filepath.Join(other, "files", "d"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(other, "info"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(other, "files", "d", "x"), []byte("abc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	content := "[Trash Info]\nPath=/home/me/d\nDeletionDate=2020-05-06T07:08:09\n"
+	if err := os.WriteFile(filepath.Join(other, "info", "d.trashinfo"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loc, err := findTrashRoot(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := s.listForeignLocation(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Size != 3 {
+		t.Fatalf("listed %v, want d of 3 bytes", files)
+	}
+
+	// Neither the cache of directory sizes nor its lock is written into the other trash
+	err = s.ImportRoot(other, func(file *trash.File, err error) {
+		if err != nil {
+			t.Errorf("failed to import %s: %v", file.OriginalPath, err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{directorySizesName, lockName} {
+		if _, err := os.Lstat(filepath.Join(other, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is written into the imported trash", name)
+		}
+	}
+}
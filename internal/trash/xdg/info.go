@@ -2,23 +2,41 @@ package xdg
 
 import (
 	"bufio"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/babarot/gomi/internal/trash"
 	"github.com/babarot/gomi/internal/utils/fs"
+	"github.com/google/uuid"
 )
 
 const (
 	// According to XDG spec
 	trashInfoHeader = "[Trash Info]"
 	timeFormat      = "2006-01-02T15:04:05"
+
+	// Keys not defined by the spec are prefixed with "X-Gomi-"
+	// so that other trash implementations can safely ignore them
+	runIDKey        = "X-Gomi-RunID"
+	compressionKey  = "X-Gomi-Compression"
+	originalSizeKey = "X-Gomi-OriginalSize"
+	importedFromKey = "X-Gomi-ImportedFrom"
+	modeKey         = "X-Gomi-Mode"
+	ownerKey        = "X-Gomi-Owner"
+	modTimeKey      = "X-Gomi-ModTime"
+	accessTimeKey   = "X-Gomi-AccessTime"
+	xattrsKey       = "X-Gomi-Xattrs"
+	checksumKey     = "X-Gomi-Checksum"
 )
 
 // TrashInfo represents the contents of a .trashinfo file
@@ -32,16 +50,38 @@ type TrashInfo struct {
 	// DeletionDate is when the file was moved to trash
 	DeletionDate time.Time
 
+	// RunID identifies the gomi invocation that moved the file to trash
+	RunID string
+
+	// Compression is how the file is compressed in the trash, empty if not compressed
+	Compression string
+
+	// OriginalSize is the size of the file before compression
+	OriginalSize int64
+
+	// ImportedFrom is where the file was stored before being imported from another trash
+	ImportedFrom string
+
+	// Metadata is the ownership, mode, times and extended attributes of the file
+	// before it was moved to trash, nil if not recorded
+	Metadata *fs.Metadata
+
+	// Checksum is the checksum of the file as stored in trash, empty if not recorded
+	Checksum string
+
 	// MountRoot is the root path of the mount point containing this trash
 	// This is used to resolve relative paths
 	MountRoot string
 }
 
-// NewInfo creates a TrashInfo from a reader
+// NewInfo creates a TrashInfo from a reader.
+// Only Path and DeletionDate make it invalid. Malformed X-Gomi-* values are
+// logged and ignored, and the metadata is dropped if any of its values is malformed,
+// as restoring a part of it would give the file a wrong mode or times.
 func NewInfo(r io.Reader) (*TrashInfo, error) {
 	scanner := bufio.NewScanner(r)
 	info := &TrashInfo{}
-	var headerFound bool
+	var headerFound, badMetadata bool
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -86,8 +126,45 @@ func NewInfo(r io.Reader) (*TrashInfo, error) {
 				return nil, fmt.Errorf("invalid DeletionDate format: %w", err)
 			}
 			info.DeletionDate = date
+
+		case runIDKey:
+			info.RunID = value
+
+		case compressionKey:
+			info.Compression = value
+
+		case originalSizeKey:
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				slog.Warn("ignoring invalid value in trash info", "key", key, "error", err)
+				continue
+			}
+			info.OriginalSize = size
+
+		case importedFromKey:
+			path, err := url.QueryUnescape(value)
+			if err != nil {
+				slog.Warn("ignoring invalid value in trash info", "key", key, "error", err)
+				continue
+			}
+			info.ImportedFrom = path
+
+		case checksumKey:
+			info.Checksum = value
+
+		case modeKey, ownerKey, modTimeKey, accessTimeKey, xattrsKey:
+			if info.Metadata == nil {
+				info.Metadata = &fs.Metadata{UID: -1, GID: -1}
+			}
+			if err := parseMetadata(info.Metadata, key, value); err != nil {
+				slog.Warn("ignoring metadata with invalid value in trash info", "key", key, "error", err)
+				badMetadata = true
+			}
 		}
 	}
+	if badMetadata {
+		info.Metadata = nil
+	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading info file: %w", err)
@@ -143,6 +220,19 @@ func (i *TrashInfo) GetRelativePath() string {
 	return rel
 }
 
+// Replace overwrites the .trashinfo at path atomically
+func (i *TrashInfo) Replace(path string) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%s.tmp", filepath.Base(path), uuid.New().String()))
+	if err := i.Save(tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // Save writes the trash info to a file atomically
 func (i *TrashInfo) Save(path string) error {
 	// Create content using relative path if mount root is available
@@ -150,6 +240,32 @@ func (i *TrashInfo) Save(path string) error {
 	fmt.Fprintln(content, trashInfoHeader)
 	fmt.Fprintf(content, "Path=%s\n", encodeTrashPath(i.GetRelativePath()))
 	fmt.Fprintf(content, "DeletionDate=%s\n", i.DeletionDate.Format(timeFormat))
+	if i.RunID != "" {
+		fmt.Fprintf(content, "%s=%s\n", runIDKey, i.RunID)
+	}
+	if i.Compression != "" {
+		fmt.Fprintf(content, "%s=%s\n", compressionKey, i.Compression)
+		fmt.Fprintf(content, "%s=%d\n", originalSizeKey, i.OriginalSize)
+	}
+	if i.ImportedFrom != "" {
+		fmt.Fprintf(content, "%s=%s\n", importedFromKey, encodeTrashPath(i.ImportedFrom))
+	}
+	if i.Checksum != "" {
+		fmt.Fprintf(content, "%s=%s\n", checksumKey, i.Checksum)
+	}
+	if m := i.Metadata; m != nil {
+		fmt.Fprintf(content, "%s=%04o\n", modeKey, unixMode(m.Mode))
+		if m.UID != -1 && m.GID != -1 {
+			fmt.Fprintf(content, "%s=%d:%d\n", ownerKey, m.UID, m.GID)
+		}
+		fmt.Fprintf(content, "%s=%s\n", modTimeKey, m.ModTime.Format(time.RFC3339Nano))
+		if !m.AccessTime.IsZero() {
+			fmt.Fprintf(content, "%s=%s\n", accessTimeKey, m.AccessTime.Format(time.RFC3339Nano))
+		}
+		if len(m.Xattrs) > 0 {
+			fmt.Fprintf(content, "%s=%s\n", xattrsKey, encodeXattrs(m.Xattrs))
+		}
+	}
 
 	// Write atomically using O_EXCL flag to prevent overwriting existing files
 	f, err := fs.Create(path, 0600)
@@ -167,6 +283,108 @@ func (i *TrashInfo) Save(path string) error {
 	return nil
 }
 
+// parseMetadata sets the field of the metadata recorded with the key
+func parseMetadata(m *fs.Metadata, key, value string) error {
+	switch key {
+	case modeKey:
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return err
+		}
+		m.Mode = fileMode(uint32(mode))
+	case ownerKey:
+		uid, gid, ok := strings.Cut(value, ":")
+		if !ok {
+			return fmt.Errorf("expected uid:gid, got %q", value)
+		}
+		var err error
+		if m.UID, err = strconv.Atoi(uid); err != nil {
+			return err
+		}
+		if m.GID, err = strconv.Atoi(gid); err != nil {
+			return err
+		}
+	case modTimeKey, accessTimeKey:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return err
+		}
+		if key == modTimeKey {
+			m.ModTime = t
+		} else {
+			m.AccessTime = t
+		}
+	case xattrsKey:
+		xattrs, err := decodeXattrs(value)
+		if err != nil {
+			return err
+		}
+		m.Xattrs = xattrs
+	}
+	return nil
+}
+
+// unixMode returns the permission and special bits of mode as in chmod(1)
+func unixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 0o1000
+	}
+	return m
+}
+
+// fileMode is the inverse of unixMode
+func fileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m) & os.ModePerm
+	if m&0o4000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// encodeXattrs encodes extended attributes as comma-separated "name:value" pairs,
+// with names percent-encoded and values in base64, sorted by name
+func encodeXattrs(xattrs map[string][]byte) string {
+	names := slices.Sorted(maps.Keys(xattrs))
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = url.QueryEscape(name) + ":" + base64.StdEncoding.EncodeToString(xattrs[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func decodeXattrs(s string) (map[string][]byte, error) {
+	xattrs := make(map[string][]byte)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected name:value, got %q", pair)
+		}
+		name, err := url.QueryUnescape(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		xattrs[name] = data
+	}
+	return xattrs, nil
+}
+
 // encodeTrashPath encodes a path according to the XDG specification:
 // - Forward slashes are not encoded
 // - Spaces are encoded as %20 (not +)
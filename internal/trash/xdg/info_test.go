@@ -0,0 +1,124 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/babarot/gomi/internal/utils/fs"
+)
+
+func TestInfoMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *fs.Metadata
+	}{
+		{
+			name:     "none",
+			metadata: nil,
+		},
+		{
+			name: "full",
+			metadata: &fs.Metadata{
+				Mode:       0o750 | os.ModeSetgid | os.ModeSticky,
+				UID:        1000,
+				GID:        100,
+				ModTime:    time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+				AccessTime: time.Date(2024, 2, 3, 4, 5, 6, 7, time.UTC),
+				Xattrs: map[string][]byte{
+					"user.comment":            []byte("a,b:c"),
+					"system.posix_acl_access": {2, 0, 0, 0, 1, 0, 6},
+				},
+			},
+		},
+		{
+			name: "unknown owner",
+			metadata: &fs.Metadata{
+				Mode:    0o644,
+				UID:     -1,
+				GID:     -1,
+				ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a.trashinfo")
+			info := &TrashInfo{
+				Path:         "/home/me/a",
+				DeletionDate: time.Now(),
+				Metadata:     tt.metadata,
+			}
+			if err := info.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadTrashInfo(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Metadata, tt.metadata) {
+				t.Errorf("Metadata = %+v, want %+v", got.Metadata, tt.metadata)
+			}
+		})
+	}
+}
+
+func TestNewInfoInvalidValues(t *testing.T) {
+	const valid = "[Trash Info]\nPath=/home/me/a\nDeletionDate=2024-01-02T03:04:05\n"
+
+	tests := []struct {
+		name    string
+		extra   string
+		wantErr bool
+		check   func(*TrashInfo) bool
+	}{
+		{
+			name:  "original size",
+			extra: "X-Gomi-Compression=zstd\nX-Gomi-OriginalSize=big\n",
+			check: func(i *TrashInfo) bool { return i.Compression == "zstd" && i.OriginalSize == 0 },
+		},
+		{
+			name:  "imported from",
+			extra: "X-Gomi-ImportedFrom=%zz\nX-Gomi-RunID=run1\n",
+			check: func(i *TrashInfo) bool { return i.ImportedFrom == "" && i.RunID == "run1" },
+		},
+		{
+			// The valid mode is not restored without the other values
+			name:  "metadata",
+			extra: "X-Gomi-Mode=644\nX-Gomi-ModTime=yesterday\n",
+			check: func(i *TrashInfo) bool { return i.Metadata == nil },
+		},
+		{
+			name:    "path",
+			extra:   "Path=%zz\n",
+			wantErr: true,
+		},
+		{
+			name:    "deletion date",
+			extra:   "DeletionDate=yesterday\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := NewInfo(strings.NewReader(valid + tt.extra))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewInfo() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if info.Path != "/home/me/a" {
+				t.Errorf("Path = %q, want /home/me/a", info.Path)
+			}
+			if !tt.check(info) {
+				t.Errorf("NewInfo() = %+v", info)
+			}
+		})
+	}
+}
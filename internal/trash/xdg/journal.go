@@ -0,0 +1,48 @@
+package xdg
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/journal"
+)
+
+// recoverJournal completes or rolls back the moves of files interrupted by a crash or a kill.
+// A put rolled back loses its .trashinfo, and a completed restore does too.
+func (s *Storage) recoverJournal() {
+	entries, err := s.journal.Pending(trash.StorageTypeXDG.String())
+	if err != nil {
+		slog.Warn("failed to read journal", "error", err)
+		return
+	}
+	for _, e := range entries {
+		done, err := e.Resolve()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to recover interrupted %s: %v\n", e, err)
+			s.journal.Release(e)
+			continue
+		}
+
+		switch {
+		case e.Op == journal.OpPut && !done:
+			if err := os.Remove(e.Info); err != nil && !os.IsNotExist(err) {
+				slog.Warn("failed to remove trash info", "path", e.Info, "error", err)
+			}
+		case e.Op == journal.OpRestore && done:
+			if err := os.Remove(e.Info); err != nil && !os.IsNotExist(err) {
+				slog.Warn("failed to remove trash info", "path", e.Info, "error", err)
+			}
+			removeDirSize(filepath.Dir(filepath.Dir(e.Src)), filepath.Base(e.Src))
+		}
+		s.journal.Commit(e)
+
+		if done {
+			slog.Info("completed interrupted operation", "entry", e.String())
+		} else {
+			slog.Info("rolled back interrupted operation", "entry", e.String())
+		}
+	}
+}
@@ -189,6 +189,12 @@ func isValidExternalTrash(path string) bool {
 		}
 	}
 
+	// $topdir/.Trash/$uid can only be used if $topdir/.Trash is valid
+	if filepath.Base(filepath.Dir(path)) == ".Trash" && !isValidSharedTrash(filepath.Dir(path)) {
+		slog.Debug("external trash directory not valid", "reason", "invalid parent .Trash", "path", path)
+		return false
+	}
+
 	// All internal directories must be mode 0700
 	for _, subdir := range []string{"files", "info"} {
 		subdirPath := filepath.Join(path, subdir)
@@ -216,6 +222,25 @@ func isValidExternalTrash(path string) bool {
 	return true
 }
 
+// isValidSharedTrash checks if $topdir/.Trash, which is shared by all users,
+// can contain per-user trash directories: it must be a directory with the
+// sticky bit set, and must not be a symbolic link
+func isValidSharedTrash(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+		slog.Debug("shared trash is not a directory", "path", path)
+		return false
+	}
+	if info.Mode()&os.ModeSticky == 0 {
+		slog.Debug("shared trash is missing sticky bit", "path", path)
+		return false
+	}
+	return true
+}
+
 // createTrashDir creates a trash directory with proper permissions
 func createTrashDir(path string) error {
 	// Create the main trash directory
@@ -249,6 +249,12 @@ func isValidExternalTrash(path string) bool {
 	return true
 }
 
+// isValidSharedTrash always returns false on Windows, which has no sticky bit,
+// so only $topdir/.Trash-$uid is created
+func isValidSharedTrash(path string) bool {
+	return false
+}
+
 // createTrashDir creates a trash directory with proper permissions
 func createTrashDir(path string) error {
 	// Create the main trash directory
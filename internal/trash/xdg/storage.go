@@ -1,14 +1,19 @@
 package xdg
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/babarot/gomi/internal/trash"
+	"github.com/babarot/gomi/internal/trash/compress"
+	"github.com/babarot/gomi/internal/trash/journal"
 	"github.com/babarot/gomi/internal/utils/fs"
 	"github.com/babarot/gomi/internal/utils/log"
 )
@@ -23,6 +28,13 @@ type Storage struct {
 
 	// Configuration
 	config trash.Config
+
+	// imported maps the paths files are being imported from to their names in home trash.
+	// It is loaded on the first import.
+	imported map[string][]string
+
+	// journal records files being moved in and out of trash
+	journal *journal.Journal
 }
 
 // trashLocation represents a single trash directory
@@ -43,6 +55,29 @@ type trashLocation struct {
 	mountRoot string
 }
 
+// movesLockName is the lock file in the trash root held shared while files are moved
+// into the trash, and exclusively while "gomi doctor" checks and fixes the trash.
+// A .trashinfo is written ahead of its file, which must not be taken for an orphan meanwhile.
+const movesLockName = ".gomi.moves.lock"
+
+// lockMoves takes the lock of the trash location held while moving a file into it
+func (loc *trashLocation) lockMoves() (func(), error) {
+	unlock, err := fs.RLock(filepath.Join(loc.root, movesLockName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock trash: %w", err)
+	}
+	return unlock, nil
+}
+
+// lockCheck takes the lock of the trash location excluding every move into it
+func (loc *trashLocation) lockCheck() (func(), error) {
+	unlock, err := fs.Lock(filepath.Join(loc.root, movesLockName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock trash: %w", err)
+	}
+	return unlock, nil
+}
+
 // NewStorage creates a new XDG-compliant trash storage
 func NewStorage(cfg trash.Config) (trash.Storage, error) {
 	slog.Info(log.UnderBold("initialize xdg storage"))
@@ -56,6 +91,13 @@ func NewStorage(cfg trash.Config) (trash.Storage, error) {
 	}
 	s.homeTrash = home
 
+	// Complete or roll back the moves interrupted by a crash or a kill
+	s.journal, err = journal.Open(cfg.JournalDir)
+	if err != nil {
+		slog.Warn("journaling is disabled", "error", err)
+	}
+	s.recoverJournal()
+
 	// Skip external trash initialization if forced to use home trash
 	if !cfg.ForceHomeTrash {
 		if err := s.scanExternalTrashes(); err != nil {
@@ -91,48 +133,68 @@ func (s *Storage) Put(src string) error {
 	if err != nil {
 		return trash.NewStorageError("put", src, err)
 	}
+	unlock, err := loc.lockMoves()
+	if err != nil {
+		return trash.NewStorageError("put", src, err)
+	}
+	defer unlock()
 
-	// Generate unique name in trash
-	baseName := filepath.Base(abs)
-	trashName := baseName
-	counter := 1
-
-	for {
-		infoPath := filepath.Join(loc.infoDir, trashName+".trashinfo")
-		filePath := filepath.Join(loc.filesDir, trashName)
-
-		// Check if name is already taken
-		_, errInfo := os.Stat(infoPath)
-		_, errFile := os.Stat(filePath)
-		if os.IsNotExist(errInfo) && os.IsNotExist(errFile) {
-			break
-		}
-
-		// Generate new name with counter
-		trashName = fmt.Sprintf("%s_%d", baseName, counter)
-		counter++
+	// Read the metadata first, as hashing the file changes its access time
+	metadata, err := fs.ReadMetadata(abs)
+	if err != nil {
+		return trash.NewStorageError("put", src, err)
 	}
 
-	// Create .trashinfo file first
+	// Files are never compressed here, as other clients of the trash would restore
+	// the compressed bytes. Compressed files of older versions are still restored.
 	info := &TrashInfo{
 		Path:         abs,
 		MountRoot:    loc.mountRoot,
 		DeletionDate: time.Now(),
+		RunID:        s.config.RunID,
+		Metadata:     metadata,
+		Checksum:     s.config.Sum(abs),
 	}
 
-	infoPath := filepath.Join(loc.infoDir, trashName+".trashinfo")
-	if err := info.Save(infoPath); err != nil {
+	// Reserve a unique name in trash
+	trashName, infoPath, err := loc.reserveName(filepath.Base(abs), info)
+	if err != nil {
 		return trash.NewStorageError("put", src, fmt.Errorf("failed to save trash info: %w", err))
 	}
 
-	// Move file to trash
 	dstPath := filepath.Join(loc.filesDir, trashName)
-	if err := fs.Move(abs, dstPath, s.config.HomeFallback); err != nil {
+
+	// Record the move, so that it is completed or rolled back if gomi is killed meanwhile
+	entry := &journal.Entry{
+		Op:      journal.OpPut,
+		Storage: trash.StorageTypeXDG.String(),
+		Src:     abs,
+		Dst:     dstPath,
+		Info:    infoPath,
+	}
+	if err := s.journal.Begin(entry); err != nil {
+		os.Remove(infoPath)
+		return trash.NewStorageError("put", src, err)
+	}
+	defer s.journal.Commit(entry)
+	copied := func() error {
+		return s.journal.Checkpoint(entry, journal.StepCopied)
+	}
+
+	// Move file to trash
+	if err := fs.MoveCheckpoint(abs, dstPath, s.config.HomeFallback, copied); err != nil {
 		// If move fails, clean up the .trashinfo file
 		os.Remove(infoPath)
 		return trash.NewStorageError("put", src, fmt.Errorf("failed to move file to trash: %w", err))
 	}
 
+	// Cache the size of the directory so that listing the trash doesn't walk it
+	if fi, err := os.Lstat(dstPath); err == nil && fi.IsDir() {
+		if size, err := fs.DirSize(dstPath); err == nil {
+			loc.updateDirSize(trashName, size)
+		}
+	}
+
 	return nil
 }
 
@@ -164,7 +226,7 @@ func (s *Storage) List() ([]*trash.File, error) {
 		files = append(files, extFiles...)
 	}
 
-	return s.filter(files), nil
+	return files, nil
 }
 
 func (s *Storage) Restore(file *trash.File, dst string) error {
@@ -177,22 +239,43 @@ func (s *Storage) Restore(file *trash.File, dst string) error {
 		return trash.NewStorageError("restore", dst, err)
 	}
 
-	// Move file back
-	if err := fs.Move(file.TrashPath, dst, s.config.HomeFallback); err != nil {
-		return trash.NewStorageError("restore", dst, err)
-	}
-
-	// Remove .trashinfo file
 	infoBaseName := filepath.Base(file.TrashPath) + ".trashinfo"
 	infoPath := filepath.Join(
 		filepath.Dir(filepath.Dir(file.TrashPath)), // Go up two levels (past "files" dir)
 		"info",
 		infoBaseName,
 	)
+
+	// Record the move, so that it is completed or rolled back if gomi is killed meanwhile
+	entry := &journal.Entry{
+		Op:      journal.OpRestore,
+		Storage: trash.StorageTypeXDG.String(),
+		Src:     file.TrashPath,
+		Dst:     journal.StagingPath(dst),
+		Target:  dst,
+		Info:    infoPath,
+	}
+	if err := s.journal.Begin(entry); err != nil {
+		return trash.NewStorageError("restore", dst, err)
+	}
+	defer s.journal.Commit(entry)
+	copied := func() error {
+		return s.journal.Checkpoint(entry, journal.StepCopied)
+	}
+
+	// Move file back, decompressing it if needed
+	if err := trash.MoveOut(file, entry.Dst, dst, s.config.HomeFallback, copied); err != nil {
+		return trash.NewStorageError("restore", dst, err)
+	}
+
+	// Remove .trashinfo file
 	if err := os.Remove(infoPath); err != nil {
 		// Log error but don't fail - file is already restored
 		fmt.Fprintf(os.Stderr, "Warning: failed to remove trash info: %v\n", err)
 	}
+	if file.IsDir && !file.IsCompressed() {
+		removeDirSize(filepath.Dir(filepath.Dir(file.TrashPath)), filepath.Base(file.TrashPath))
+	}
 
 	return nil
 }
@@ -214,23 +297,30 @@ func (s *Storage) Remove(file *trash.File) error {
 		// Log error but don't fail - file is already removed
 		fmt.Fprintf(os.Stderr, "Warning: failed to remove trash info: %v\n", err)
 	}
+	if file.IsDir && !file.IsCompressed() {
+		removeDirSize(filepath.Dir(filepath.Dir(file.TrashPath)), filepath.Base(file.TrashPath))
+	}
 
 	return nil
 }
 
 func (s *Storage) initHomeTrash() (*trashLocation, error) {
-	var root string
-
-	dataDir := os.Getenv("XDG_DATA_HOME")
-	if dataDir == "" {
-		// Fallback to ~/.local/share
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+	// HomeTrashDir is defaulted to $XDG_DATA_HOME/Trash by trash.Config.Validate,
+	// and can be set to another location with core.trash.home_dir
+	root := s.config.HomeTrashDir
+	if root == "" {
+		dataDir := os.Getenv("XDG_DATA_HOME")
+		if dataDir == "" {
+			// Fallback to ~/.local/share
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get home directory: %w", err)
+			}
+			dataDir = filepath.Join(home, ".local", "share")
 		}
-		dataDir = filepath.Join(home, ".local", "share")
+		root = filepath.Join(dataDir, "Trash")
 	}
-	root = filepath.Join(dataDir, "Trash")
+	slog.Debug("home trash", "root", root)
 
 	loc := &trashLocation{
 		root:     root,
@@ -265,7 +355,7 @@ func (s *Storage) scanExternalTrashes() error {
 	for _, mount := range mounts {
 		// Check for $topdir/.Trash/$uid
 		trashPath := filepath.Join(mount, ".Trash", uidStr)
-		if isValidExternalTrash(trashPath) {
+		if s.isExternalTrash(trashPath) {
 			loc := &trashLocation{
 				root:      trashPath,
 				filesDir:  filepath.Join(trashPath, "files"),
@@ -279,7 +369,7 @@ func (s *Storage) scanExternalTrashes() error {
 
 		// Check for $topdir/.Trash-$uid
 		trashPath = filepath.Join(mount, fmt.Sprintf(".Trash-%d", uid))
-		if isValidExternalTrash(trashPath) {
+		if s.isExternalTrash(trashPath) {
 			loc := &trashLocation{
 				root:      trashPath,
 				filesDir:  filepath.Join(trashPath, "files"),
@@ -294,7 +384,26 @@ func (s *Storage) scanExternalTrashes() error {
 	return nil
 }
 
+// isExternalTrash reports whether the path is a valid external trash
+// other than the home trash, which may be placed on any mount point
+func (s *Storage) isExternalTrash(path string) bool {
+	return path != s.homeTrash.root && isValidExternalTrash(path)
+}
+
 func (s *Storage) listLocation(loc *trashLocation) ([]*trash.File, error) {
+	return s.readLocation(loc, true)
+}
+
+// listForeignLocation lists a trash directory not managed by gomi without writing
+// anything to it, so that read-only ones can be listed as well.
+// Sizes of directories missing from its directorysizes cache are calculated but not saved.
+func (s *Storage) listForeignLocation(loc *trashLocation) ([]*trash.File, error) {
+	return s.readLocation(loc, false)
+}
+
+// readLocation lists the files of the trash location,
+// updating its directorysizes cache if saveSizes is true
+func (s *Storage) readLocation(loc *trashLocation, saveSizes bool) ([]*trash.File, error) {
 	var files []*trash.File
 
 	// Read files directory
@@ -303,9 +412,20 @@ func (s *Storage) listLocation(loc *trashLocation) ([]*trash.File, error) {
 		return nil, fmt.Errorf("failed to read files directory: %w", err)
 	}
 
+	// Sizes of directories are read from the directorysizes cache,
+	// and the ones missing from it are calculated and added
+	sizes, err := loadDirSizes(loc.root)
+	if err != nil {
+		slog.Warn("failed to load directory sizes", "root", loc.root, "error", err)
+		sizes = dirSizes{}
+	}
+	cached := len(sizes)
+	added := dirSizes{}
+
 	for _, entry := range entries {
 		// Load corresponding .trashinfo file
-		info, err := loadTrashInfo(filepath.Join(loc.infoDir, entry.Name()+".trashinfo"))
+		infoPath := filepath.Join(loc.infoDir, entry.Name()+".trashinfo")
+		info, err := loadTrashInfo(infoPath)
 		if err != nil {
 			// Skip files without valid info
 			continue
@@ -333,14 +453,92 @@ func (s *Storage) listLocation(loc *trashLocation) ([]*trash.File, error) {
 			Size:         fileInfo.Size(),
 			IsDir:        fileInfo.IsDir(),
 			FileMode:     fileInfo.Mode(),
+			RunID:        info.RunID,
+			Compression:  info.Compression,
+			Metadata:     info.Metadata,
+			Checksum:     info.Checksum,
+		}
+		if m, err := compress.ParseMethod(info.Compression); err == nil {
+			file.Size = info.OriginalSize
+			file.IsDir = m.Tar
+		} else if entry.IsDir() {
+			size, ok := sizes.lookup(entry.Name(), infoPath)
+			if !ok {
+				if size, err = fs.DirSize(filePath); err == nil {
+					_ = added.set(entry.Name(), size, infoPath)
+				}
+			}
+			file.Size = size
+			file.SetStoredSize(size)
+		}
+		if info.Metadata != nil {
+			// The mode in the trash is the one of the compressed file, if compressed
+			file.FileMode = info.Metadata.Mode
+			if file.IsDir {
+				file.FileMode |= os.ModeDir
+			}
 		}
 		file.SetStorage(s)
 		files = append(files, file)
 	}
 
+	if !saveSizes {
+		return files, nil
+	}
+
+	// Drop entries of directories no longer in the trash
+	var removed []string
+	for name := range sizes {
+		if _, err := os.Lstat(filepath.Join(loc.filesDir, name)); os.IsNotExist(err) {
+			removed = append(removed, name)
+		}
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		err := updateDirSizes(loc.root, func(sizes dirSizes) bool {
+			maps.Copy(sizes, added)
+			for _, name := range removed {
+				delete(sizes, name)
+			}
+			return true
+		})
+		if err != nil {
+			slog.Debug("failed to save directory sizes", "root", loc.root, "error", err)
+		}
+	}
+	slog.Debug("directory sizes", "root", loc.root, "cached", cached, "added", len(added), "removed", len(removed))
+
 	return files, nil
 }
 
+// reserveName saves the trash info under a name taken by neither a file nor
+// a .trashinfo in the location, and returns the name and the path of the info.
+// The .trashinfo is created exclusively, so it reserves the name against other
+// processes picking the same one, and the next name is tried on collision.
+func (loc *trashLocation) reserveName(baseName string, info *TrashInfo) (string, string, error) {
+	trashName := baseName
+	counter := 1
+
+	for {
+		infoPath := filepath.Join(loc.infoDir, trashName+".trashinfo")
+		filePath := filepath.Join(loc.filesDir, trashName)
+
+		// A file without .trashinfo still takes the name
+		if _, err := os.Lstat(filePath); os.IsNotExist(err) {
+			err := info.Save(infoPath)
+			if err == nil {
+				return trashName, infoPath, nil
+			}
+			if !errors.Is(err, os.ErrExist) {
+				return "", "", err
+			}
+		}
+
+		// Generate new name with counter
+		trashName = fmt.Sprintf("%s_%d", baseName, counter)
+		counter++
+	}
+}
+
 func (s *Storage) selectTrashLocation(path string) (*trashLocation, error) {
 	// Check if file is on the same device as home trash
 	sameDevice, err := isOnSameDevice(path, s.homeTrash.root)
@@ -356,6 +554,15 @@ func (s *Storage) selectTrashLocation(path string) (*trashLocation, error) {
 		}
 	}
 
+	// Create a trash directory on the mount point of the file
+	if s.config.CreateExternalTrash && !s.config.ForceHomeTrash {
+		loc, err := s.createExternalTrash(path)
+		if err == nil {
+			return loc, nil
+		}
+		slog.Warn("failed to create external trash", "path", path, "error", err)
+	}
+
 	// If home fallback is enabled, use home trash
 	if s.config.HomeFallback {
 		return s.homeTrash, nil
@@ -364,11 +571,85 @@ func (s *Storage) selectTrashLocation(path string) (*trashLocation, error) {
 	return nil, trash.ErrCrossDevice
 }
 
-func (s *Storage) filter(files []*trash.File) []*trash.File {
-	opts := trash.FilterOptions{
-		Include: s.config.History.Include,
-		Exclude: s.config.History.Exclude,
+// createExternalTrash creates a trash directory on the mount point of path,
+// following the XDG spec: $topdir/.Trash/$uid if $topdir/.Trash is a valid
+// shared trash, otherwise $topdir/.Trash-$uid.
+// An invalid .Trash, such as one without the sticky bit, is never written into.
+func (s *Storage) createExternalTrash(path string) (*trashLocation, error) {
+	topdir, err := findTopDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := os.Getuid()
+	var candidates []string
+	shared := filepath.Join(topdir, ".Trash")
+	if isValidSharedTrash(shared) {
+		candidates = append(candidates, filepath.Join(shared, strconv.Itoa(uid)))
+	} else if _, err := os.Lstat(shared); err == nil {
+		slog.Warn("ignoring invalid shared trash", "path", shared)
+	}
+	candidates = append(candidates, filepath.Join(topdir, fmt.Sprintf(".Trash-%d", uid)))
+
+	var errs []error
+	for _, root := range candidates {
+		// An existing path is not a valid trash, otherwise it would have been found by
+		// scanExternalTrashes, so it is left as is rather than being turned into one
+		if _, err := os.Lstat(root); err == nil && !isValidExternalTrash(root) {
+			errs = append(errs, fmt.Errorf("%s exists but is not a valid trash directory", root))
+			continue
+		}
+		if err := createTrashDir(root); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !isValidExternalTrash(root) {
+			errs = append(errs, fmt.Errorf("%s is not a valid trash directory", root))
+			continue
+		}
+
+		loc := &trashLocation{
+			root:      root,
+			filesDir:  filepath.Join(root, "files"),
+			infoDir:   filepath.Join(root, "info"),
+			isHome:    false,
+			mountRoot: topdir,
+		}
+		s.externalTrashes = append(s.externalTrashes, loc)
+		slog.Info("created external trash", "root", root)
+		return loc, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// findTopDir returns the writable mount point containing path
+func findTopDir(path string) (string, error) {
+	mounts, err := getMountPoints()
+	if err != nil {
+		return "", fmt.Errorf("failed to get mount points: %w", err)
+	}
+
+	// The file itself may be a symlink pointing to another device,
+	// so the device is checked with its parent directory
+	dir := filepath.Dir(path)
+	var topdir string
+	for _, mount := range mounts {
+		prefix := strings.TrimSuffix(mount, string(filepath.Separator)) + string(filepath.Separator)
+		if dir != mount && !strings.HasPrefix(dir, prefix) {
+			continue
+		}
+		if len(mount) <= len(topdir) {
+			continue
+		}
+		// Mount points skipped by getMountPoints (e.g. read-only or tmpfs)
+		// must not be mistaken for the mount point above them
+		if same, err := isOnSameDevice(dir, mount); err != nil || !same {
+			continue
+		}
+		topdir = mount
+	}
+	if topdir == "" {
+		return "", fmt.Errorf("no writable mount point found for %s", path)
 	}
-	slog.Debug("xdg filter items", "len(files)", len(files))
-	return trash.Filter(files, opts)
+	return topdir, nil
 }
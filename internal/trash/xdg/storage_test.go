@@ -0,0 +1,55 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReserveName(t *testing.T) {
+	s := newTestStorage(t)
+	loc := s.homeTrash
+
+	// A file left without .trashinfo still takes its name
+	if err := os.WriteFile(filepath.Join(loc.filesDir, "a_1"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		names = make(map[string]bool)
+	)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info := &TrashInfo{Path: "/home/me/a", DeletionDate: time.Now()}
+			name, infoPath, err := loc.reserveName("a", info)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := os.Stat(infoPath); err != nil {
+				t.Errorf("%s is not saved: %v", infoPath, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if names[name] {
+				t.Errorf("%q is reserved more than once", name)
+			}
+			names[name] = true
+		}()
+	}
+	wg.Wait()
+
+	if len(names) != n {
+		t.Errorf("reserved %d names, want %d", len(names), n)
+	}
+	if names["a_1"] {
+		t.Error("reserved a name taken by a file")
+	}
+}
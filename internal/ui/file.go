@@ -10,7 +10,6 @@ import (
 	"strings"
 
 	"github.com/babarot/gomi/internal/trash"
-	"github.com/babarot/gomi/internal/utils/fs"
 	"github.com/babarot/gomi/internal/utils/shell"
 
 	"al.essio.dev/pkg/shellescape"
@@ -53,7 +52,8 @@ func (f File) Title() string {
 	if err != nil {
 		return f.File.Name + "?"
 	}
-	if fi.IsDir() {
+	// A compressed directory is stored as a single file
+	if fi.IsDir() || f.File.IsCompressed() && f.File.IsDir {
 		return f.File.Name + "/"
 	}
 	return f.File.Name
@@ -65,9 +65,12 @@ func (f File) FilterValue() string {
 
 func (f File) Size() string {
 	var sizeStr string
-	size, err := fs.DirSize(f.TrashPath)
+	size, err := f.File.StoredSize()
 	if err != nil {
 		sizeStr = "(cannot be calculated)"
+	} else if f.File.IsCompressed() {
+		sizeStr = fmt.Sprintf("%s (%s compressed)",
+			humanize.Bytes(uint64(f.File.Size)), humanize.Bytes(uint64(size)))
 	} else {
 		sizeStr = humanize.Bytes(uint64(size))
 	}
@@ -82,14 +85,14 @@ func (f File) Browse() (string, error) {
 		slog.Debug("no such file", "file", f.TrashPath)
 		return content, errCannotPreview
 	}
-	if fi.IsDir() {
-		if f.dirListCommand == "" {
-			slog.Debug("preview dir command is not set, fallback to builtin dir func")
+	if fi.IsDir() || f.IsCompressed() && f.IsDir {
+		// The command cannot list a compressed directory, which is stored as a single file
+		if f.dirListCommand == "" || f.IsCompressed() {
+			slog.Debug("preview dir command is not set or dir is compressed, fallback to builtin dir func")
 			lines := []string{}
-			dirs, _ := os.ReadDir(f.TrashPath)
-			for _, dir := range dirs {
-				info, _ := dir.Info()
-				name := dir.Name()
+			infos, _ := f.File.ReadDir()
+			for _, info := range infos {
+				name := info.Name()
 				if info.IsDir() {
 					name += "/"
 				}
@@ -111,7 +114,7 @@ func (f File) Browse() (string, error) {
 		}
 		return out, err
 	}
-	mtype, err := mimetype.DetectFile(f.TrashPath)
+	mtype, err := f.detectMIME()
 	if err != nil {
 		return content, err
 	}
@@ -121,7 +124,8 @@ func (f File) Browse() (string, error) {
 		slog.Debug("cannot preview", "mimetype", mtype.String())
 		return content, errCannotPreview
 	}
-	fp, err := os.Open(f.TrashPath)
+	// Compressed files are streamed through the decompressor
+	fp, err := f.File.Open()
 	if err != nil {
 		return content, errCannotPreview
 	}
@@ -141,6 +145,21 @@ func (f File) Browse() (string, error) {
 	return content, nil
 }
 
+// detectMIME detects the MIME type of the file content,
+// decompressing the head of the file if it is compressed
+func (f File) detectMIME() (*mimetype.MIME, error) {
+	if !f.IsCompressed() {
+		return mimetype.DetectFile(f.TrashPath)
+	}
+	r, err := f.File.Open()
+	if err != nil {
+		// Report the type of the compressed file itself
+		return mimetype.DetectFile(f.TrashPath)
+	}
+	defer r.Close()
+	return mimetype.DetectReader(r)
+}
+
 func (f File) colorize(content string) (string, error) {
 	defer color.Unset()
 	var l chroma.Lexer
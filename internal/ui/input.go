@@ -3,23 +3,29 @@ package ui
 import (
 	"errors"
 	"fmt"
-	"regexp"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/babarot/gomi/internal/trash"
 	"github.com/babarot/gomi/internal/ui/input"
+	"github.com/babarot/gomi/internal/utils/shell"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jimschubert/answer/validate"
 )
 
-func InputFilename(file *trash.File) (string, error) {
+// InputFilename asks for a new name to restore the file with, as dst exists.
+// Any name is accepted as long as it is a single path component not taken in the directory.
+func InputFilename(file *trash.File, dst string) (string, error) {
+	dir := filepath.Dir(dst)
+
 	m := input.New()
 	m.Prompt = "New name to avoid to overwrite:"
 	m.Placeholder = file.Name
 	m.Validate = validate.NewValidation().
 		MinLength(1, "min: 1 characters").
 		And(func(input string) error {
-			if strings.ToLower(input) == file.Name {
+			if input == file.Name {
 				return errors.New("name should be changed")
 			}
 			return nil
@@ -28,17 +34,7 @@ func InputFilename(file *trash.File) (string, error) {
 			if input == "" {
 				return nil
 			}
-			matched, err := regexp.MatchString(`^[a-zA-Z0-9._-]*$`, input)
-			if err != nil {
-				return fmt.Errorf("regexp failed: %w", err)
-			}
-			if !matched {
-				return errors.New("not valid chars are included")
-			}
-			if onlySpecialChars(input) {
-				return errors.New("using only special characters is not allowed")
-			}
-			return nil
+			return validFilename(dir, input)
 		}).
 		Build()
 
@@ -54,11 +50,136 @@ func InputFilename(file *trash.File) (string, error) {
 	return m.Value(), nil
 }
 
-func onlySpecialChars(input string) bool {
-	for _, char := range input {
-		if char != '.' && char != '_' && char != '-' {
-			return false
+// validFilename checks that name can be given to a file in dir
+func validFilename(dir, name string) error {
+	switch {
+	case name == "." || name == "..":
+		return errors.New("not a file name")
+	case strings.ContainsRune(name, 0):
+		return errors.New("not valid chars are included")
+	case strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator):
+		return errors.New("path separators are not allowed")
+	case strings.TrimSpace(name) == "":
+		return errors.New("using only spaces is not allowed")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+		return fmt.Errorf("%s already exists", name)
+	}
+	return nil
+}
+
+// InputDirectory asks for an existing directory to restore files into,
+// completing the path as it is typed. It returns the absolute path of the directory.
+func InputDirectory() (string, error) {
+	m := input.New()
+	m.Prompt = "Restore into directory:"
+	m.Placeholder = "path (tab to complete)"
+	m.Complete = completeDir
+	m.Validate = validate.NewValidation().
+		MinLength(1, "min: 1 characters").
+		And(func(input string) error {
+			if input == "" {
+				return nil
+			}
+			_, err := ExpandDir(input)
+			return err
+		}).
+		Build()
+
+	p := tea.NewProgram(&m)
+	if _, err := p.Run(); err != nil {
+		return "", err
+	}
+
+	if m.Canceled() {
+		return "", ErrInputCanceled
+	}
+	return ExpandDir(m.Value())
+}
+
+// ExpandDir returns the absolute path of dir, expanding "~" and environment variables,
+// and fails unless it is an existing directory
+func ExpandDir(dir string) (string, error) {
+	expanded, err := shell.ExpandHome(dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(expanded)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s does not exist", dir)
+		}
+		return "", err
+	}
+	if !fi.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return abs, nil
+}
+
+// completeDir returns the directories whose path starts with input, as typed
+// with a trailing separator, so that accepting one goes on completing its entries
+func completeDir(input string) []string {
+	// The part after the last separator is completed in the directory before it
+	typedDir := input[:strings.LastIndexAny(input, "/"+string(filepath.Separator))+1]
+	dir := typedDir
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := shell.ExpandHome(dir)
+	if err != nil {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	prefix := input[len(typedDir):]
+	var completions []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
 		}
+		// Hidden directories are only completed once a dot is typed
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || !fi.IsDir() {
+			continue
+		}
+		completions = append(completions, typedDir+name+string(filepath.Separator))
+	}
+	return completions
+}
+
+// InputConfirmation asks the user to type the expected text to go ahead
+// with a dangerous operation. It returns ErrInputCanceled if canceled.
+func InputConfirmation(prompt, expected string) error {
+	m := input.New()
+	m.Prompt = prompt
+	m.Placeholder = expected
+	m.Validate = validate.NewValidation().
+		And(func(input string) error {
+			if input != expected {
+				return fmt.Errorf("type %q to confirm", expected)
+			}
+			return nil
+		}).
+		Build()
+
+	p := tea.NewProgram(&m)
+	if _, err := p.Run(); err != nil {
+		return err
+	}
+
+	if m.Canceled() {
+		return ErrInputCanceled
 	}
-	return true
+	return nil
 }
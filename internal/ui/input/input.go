@@ -24,6 +24,8 @@ import (
   - This change was made to prevent issues where, if empty input is not allowed,
     the user could press Enter before typing anything, causing validation to not
     trigger properly or leading to unclear error messages about which validation failed.
+  - Added Complete, which offers completions of the input inline
+    (accepted with tab and cycled with up/down), e.g. for paths.
 */
 
 var (
@@ -71,11 +73,13 @@ type Model struct {
 	Validate         ValidateFunc
 	Styles           Styles
 	Suggest          func(input string) []string
+	Complete         func(input string) []string
 	SuggestionPrefix string
 	err              error
 	done             exitType
 	input            textinput.Model
 	initialized      bool
+	completed        bool
 	suggestions      []string
 	keyMap           keyMap
 }
@@ -121,6 +125,7 @@ func (m *Model) setup() {
 	input.PlaceholderStyle = m.Styles.Placeholder
 	input.TextStyle = m.Styles.Text
 	input.EchoMode = m.EchoMode
+	input.ShowSuggestions = m.Complete != nil
 	input.Focus()
 	m.input = input
 	m.initialized = true
@@ -177,6 +182,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if changed {
 		m.err = m.Validate(m.input.Value())
 	}
+	if m.Complete != nil && (changed || !m.completed) {
+		m.input.SetSuggestions(m.Complete(after))
+		m.completed = true
+	}
 
 	// Originally, validation runs after the text is entered,
 	// but with this change,
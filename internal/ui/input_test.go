@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompleteDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"src", "srv", ".git", "docs"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	prefix := dir + string(filepath.Separator)
+	sep := string(filepath.Separator)
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{prefix, []string{prefix + "docs" + sep, prefix + "src" + sep, prefix + "srv" + sep}},
+		{prefix + "sr", []string{prefix + "src" + sep, prefix + "srv" + sep}},
+		{prefix + "src", []string{prefix + "src" + sep}},
+		{prefix + ".", []string{prefix + ".git" + sep}},
+		{prefix + "x", nil},
+		{prefix + "missing/", nil},
+	}
+	for _, tt := range tests {
+		got := completeDir(tt.input)
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("completeDir(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -3,13 +3,14 @@ package keys
 import "github.com/charmbracelet/bubbles/key"
 
 type ListKeyMap struct {
-	Quit     key.Binding
-	Enter    key.Binding
-	Space    key.Binding
-	Select   key.Binding
-	DeSelect key.Binding
-	Delete   key.Binding
-	Esc      key.Binding
+	Quit      key.Binding
+	Enter     key.Binding
+	RestoreTo key.Binding
+	Space     key.Binding
+	Select    key.Binding
+	DeSelect  key.Binding
+	Delete    key.Binding
+	Esc       key.Binding
 
 	showDelete bool
 }
@@ -25,6 +26,7 @@ func (k ListKeyMap) ShortHelp() []key.Binding {
 func (k ListKeyMap) FullHelp() [][]key.Binding {
 	keys := append(
 		k.ShortHelp(),
+		k.RestoreTo,
 		k.DeSelect,
 		k.Esc,
 	)
@@ -54,6 +56,10 @@ var ListKeys = &ListKeyMap{
 		key.WithKeys("enter"),
 		key.WithHelp("enter", "ok"),
 	),
+	RestoreTo: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "restore to"),
+	),
 	Space: key.NewBinding(
 		key.WithKeys(" "),
 		key.WithHelp("space", "detail"),
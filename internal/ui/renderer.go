@@ -11,7 +11,6 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 	"github.com/dustin/go-humanize"
-	"github.com/gabriel-vasile/mimetype"
 	"github.com/muesli/reflow/wordwrap"
 	"github.com/muesli/termenv"
 	"github.com/samber/lo"
@@ -126,7 +125,7 @@ func (m Model) previewFooter() string {
 func (m Model) renderPreview() string {
 	content := m.viewport.View()
 	if m.cannotPreview {
-		mtype, _ := mimetype.DetectFile(m.detailFile.TrashPath)
+		mtype, _ := m.detailFile.detectMIME()
 		verticalMarginHeight := lipgloss.Height(m.previewHeader())
 		content = lipgloss.Place(defaultWidth, 15-verticalMarginHeight,
 			lipgloss.Center, lipgloss.Center,
@@ -111,6 +111,9 @@ type Model struct {
 	config  config.UI
 	choices []File
 
+	// restoreTo is true when the choices are restored into another directory
+	restoreTo bool
+
 	styles dialogStyles
 
 	help     help.Model
@@ -342,7 +345,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.setViewType(LIST_VIEW)
 			}
 
-		case key.Matches(msg, m.listKeys.Enter):
+		case key.Matches(msg, m.listKeys.Enter, m.listKeys.RestoreTo):
 			switch m.viewType {
 			case LIST_VIEW:
 				if m.list.FilterState() != list.Filtering {
@@ -355,7 +358,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					} else {
 						m.choices = files
 					}
-					slog.Debug("key input: enter", slog.Any("selected_files", m.choices))
+					m.restoreTo = key.Matches(msg, m.listKeys.RestoreTo)
+					slog.Debug("key input: enter", slog.Any("selected_files", m.choices), "restore_to", m.restoreTo)
 					return m, tea.Quit
 				}
 			}
@@ -460,7 +464,10 @@ func (m *Model) newViewportModel(file File) viewport.Model {
 	return viewportModel
 }
 
-func RenderList(manager *trash.Manager, filteredFiles []*trash.File, c *config.Config) ([]*trash.File, error) {
+// RenderList shows the files and returns the ones chosen to be restored.
+// When they are chosen with the "restore to" action, it also asks for
+// the directory to restore them into and returns it, otherwise the directory is empty.
+func RenderList(manager *trash.Manager, filteredFiles []*trash.File, c *config.Config) ([]*trash.File, string, error) {
 	cfg := c.UI
 	var items []list.Item
 	var files []File
@@ -515,7 +522,7 @@ func RenderList(manager *trash.Manager, filteredFiles []*trash.File, c *config.C
 
 	returnModel, err := tea.NewProgram(m).Run()
 	if err != nil {
-		return []*trash.File{}, err
+		return []*trash.File{}, "", err
 	}
 
 	choices := returnModel.(Model).choices
@@ -523,14 +530,25 @@ func RenderList(manager *trash.Manager, filteredFiles []*trash.File, c *config.C
 		if msg := cfg.ExitMessage; msg != "" {
 			fmt.Println(msg)
 		}
-		return []*trash.File{}, nil
+		return []*trash.File{}, "", nil
+	}
+
+	var dir string
+	if returnModel.(Model).restoreTo && len(choices) > 0 {
+		dir, err = InputDirectory()
+		if errors.Is(err, ErrInputCanceled) {
+			return []*trash.File{}, "", nil
+		}
+		if err != nil {
+			return []*trash.File{}, "", err
+		}
 	}
 
 	trashFiles := make([]*trash.File, len(choices))
 	for i, file := range choices {
 		trashFiles[i] = file.File
 	}
-	return trashFiles, nil
+	return trashFiles, dir, nil
 }
 
 func (m Model) deletePermanentlyCmd(files ...File) tea.Cmd {
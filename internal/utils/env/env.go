@@ -15,6 +15,12 @@ var (
 	GOMI_CONFIG_PATH string
 
 	GOMI_LOG_PATH string
+
+	// GOMI_AUDIT_LOG_PATH records files deleted without going to trash
+	GOMI_AUDIT_LOG_PATH string
+
+	// GOMI_JOURNAL_DIR records files being moved in and out of trash
+	GOMI_JOURNAL_DIR string
 )
 
 func init() {
@@ -49,4 +55,16 @@ func init() {
 	} else {
 		GOMI_LOG_PATH = os.Getenv("GOMI_LOG_PATH")
 	}
+
+	if e := os.Getenv("GOMI_AUDIT_LOG_PATH"); e == "" {
+		GOMI_AUDIT_LOG_PATH = filepath.Join(filepath.Dir(GOMI_LOG_PATH), "audit.log")
+	} else {
+		GOMI_AUDIT_LOG_PATH = e
+	}
+
+	if e := os.Getenv("GOMI_JOURNAL_DIR"); e == "" {
+		GOMI_JOURNAL_DIR = filepath.Join(filepath.Dir(GOMI_LOG_PATH), "journal")
+	} else {
+		GOMI_JOURNAL_DIR = e
+	}
 }
@@ -5,8 +5,6 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-
-	cp "github.com/otiai10/copy"
 )
 
 // Create creates a new file with O_EXCL flag to ensure atomic creation.
@@ -20,6 +18,13 @@ func Create(path string, perm os.FileMode) (*os.File, error) {
 // If the move fails due to being on different devices and fallbackCopy is true,
 // it will fall back to copy and delete.
 func Move(src, dst string, fallbackCopy bool) error {
+	return MoveCheckpoint(src, dst, fallbackCopy, nil)
+}
+
+// MoveCheckpoint is Move calling copied, if not nil, once a complete copy of src
+// is at dst and before src is removed, when it falls back to copy and delete.
+// If copied fails, the copy is removed and src is left as it is.
+func MoveCheckpoint(src, dst string, fallbackCopy bool, copied func() error) error {
 	// Ensure the destination directory exists
 	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
@@ -32,10 +37,19 @@ func Move(src, dst string, fallbackCopy bool) error {
 		}
 
 		// Fallback to copy and delete
-		if err := cp.Copy(src, dst); err != nil {
+		if err := Copy(src, dst); err != nil {
 			return fmt.Errorf("failed to copy file: %w", err)
 		}
 
+		if copied != nil {
+			if err := copied(); err != nil {
+				if err := os.RemoveAll(dst); err != nil {
+					slog.Error("failed to remove the copy", "path", dst)
+				}
+				return err
+			}
+		}
+
 		// If copy succeeds, remove the original
 		if err := os.RemoveAll(src); err != nil {
 			// If we can't remove the source, try to remove the copy
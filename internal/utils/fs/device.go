@@ -0,0 +1,32 @@
+//go:build !windows
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Device returns the ID of the device containing the file at path.
+// Symbolic links are not followed.
+func Device(path string) (uint64, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("failed to get device information: %s", path)
+	}
+	return uint64(stat.Dev), nil //nolint:unconvert // Dev is int32 on some platforms
+}
+
+// LinkCount returns the number of hard links to the file
+func LinkCount(fi os.FileInfo) uint64 {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 1
+	}
+	return uint64(stat.Nlink) //nolint:unconvert // Nlink is uint16 on some platforms
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+// Device is not supported on Windows, where drives are not mounted
+// into a single hierarchy
+func Device(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// LinkCount returns 1, as hard links are not counted on Windows
+func LinkCount(fi os.FileInfo) uint64 {
+	return 1
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package fs
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Lock takes an exclusive lock (LockFileEx) on the file at path, creating it if needed.
+// It blocks until the lock is acquired, and the returned function releases it.
+func Lock(path string) (func(), error) {
+	return lock(path, windows.LOCKFILE_EXCLUSIVE_LOCK)
+}
+
+// RLock is Lock taking a shared lock, which excludes exclusive locks only
+func RLock(path string) (func(), error) {
+	return lock(path, 0)
+}
+
+func lock(path string, flags uint32) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	h := windows.Handle(f.Fd())
+	ol := new(windows.Overlapped)
+	if err := windows.LockFileEx(h, flags, 0, 1, 0, ol); err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "lock", Path: path, Err: err}
+	}
+	return func() {
+		_ = windows.UnlockFileEx(h, 0, 1, 0, ol)
+		f.Close()
+	}, nil
+}
+
+// TryLock is Lock returning false instead of blocking when the lock is held elsewhere
+func TryLock(path string) (func(), bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, false, err
+	}
+	h := windows.Handle(f.Fd())
+	ol := new(windows.Overlapped)
+	err = windows.LockFileEx(h, windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		f.Close()
+		return nil, false, nil
+	}
+	if err != nil {
+		f.Close()
+		return nil, false, &os.PathError{Op: "lock", Path: path, Err: err}
+	}
+	return func() {
+		_ = windows.UnlockFileEx(h, 0, 1, 0, ol)
+		f.Close()
+	}, true, nil
+}
@@ -0,0 +1,177 @@
+package fs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	cp "github.com/otiai10/copy"
+)
+
+// Metadata is the metadata of a file that a copy to another device may lose.
+// It is recorded when a file is moved to the trash and reapplied on restore.
+type Metadata struct {
+	// Mode is the permission and special bits of the file
+	Mode os.FileMode `json:"mode"`
+
+	// UID and GID are the owner of the file, -1 if unknown
+	UID int `json:"uid"`
+	GID int `json:"gid"`
+
+	ModTime    time.Time `json:"mtime"`
+	AccessTime time.Time `json:"atime"`
+
+	// Xattrs are the extended attributes of the file, which include POSIX ACLs on Linux
+	Xattrs map[string][]byte `json:"xattrs,omitempty"`
+}
+
+// ReadMetadata returns the metadata of the file at path. Symbolic links are not followed.
+// Extended attributes that cannot be read are left out.
+func ReadMetadata(path string) (*Metadata, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Metadata{
+		Mode:    fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky),
+		UID:     -1,
+		GID:     -1,
+		ModTime: fi.ModTime(),
+	}
+	m.readSys(path)
+	m.Xattrs = listXattrs(path)
+	return m, nil
+}
+
+// Apply sets the metadata on the file at path, changing only what differs.
+// It sets every attribute it can, and returns an *ApplyError naming those it could not set.
+func (m *Metadata) Apply(path string) error {
+	current, err := ReadMetadata(path)
+	if err != nil {
+		return err
+	}
+	symlink := isSymlink(path)
+
+	var failed []string
+	var errs []error
+	fail := func(attr string, err error) {
+		failed = append(failed, attr)
+		errs = append(errs, fmt.Errorf("%s: %w", attr, err))
+	}
+
+	// The owner is set first, as changing it clears the setuid and setgid bits
+	// and file capabilities
+	if m.UID != -1 && m.GID != -1 && (m.UID != current.UID || m.GID != current.GID) {
+		if err := os.Lchown(path, m.UID, m.GID); err != nil {
+			fail("owner", err)
+		}
+		if c, err := ReadMetadata(path); err == nil {
+			current = c
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.Xattrs)) {
+		value := m.Xattrs[name]
+		if v, ok := current.Xattrs[name]; ok && bytes.Equal(v, value) {
+			continue
+		}
+		if err := setXattr(path, name, value); err != nil {
+			fail("xattr "+name, err)
+		}
+	}
+	// The mode of a symbolic link is not used, and chmod would change its target
+	if !symlink && m.Mode != current.Mode {
+		if err := os.Chmod(path, m.Mode); err != nil {
+			fail("mode", err)
+		}
+	}
+	// Times are set last, as setting the other attributes may change them
+	if !m.ModTime.Equal(current.ModTime) || !m.AccessTime.Equal(current.AccessTime) {
+		atime := m.AccessTime
+		if atime.IsZero() {
+			atime = m.ModTime
+		}
+		if err := lchtimes(path, atime, m.ModTime); err != nil {
+			fail("times", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ApplyError{Path: path, Attrs: failed, Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+// ApplyError is returned by Metadata.Apply when some attributes could not be set
+type ApplyError struct {
+	Path  string
+	Attrs []string
+	Err   error
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("could not set %v of %s: %v", e.Attrs, e.Path, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+func isSymlink(path string) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && fi.Mode()&os.ModeSymlink != 0
+}
+
+// Copy copies the file or directory src to dst, keeping the metadata of every entry
+// as far as possible. Attributes that cannot be set on the copy, such as the owner
+// when not running as root, are logged and do not fail the copy.
+func Copy(src, dst string) error {
+	// The metadata is read before copying, as reading the files changes their access time
+	type entry struct {
+		rel      string
+		metadata *Metadata
+	}
+	var entries []entry
+	err := filepath.WalkDir(src, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		metadata, err := ReadMetadata(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{rel: rel, metadata: metadata})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := cp.Copy(src, dst); err != nil {
+		return err
+	}
+
+	// Entries are applied after their parent directory is complete,
+	// and directories after their entries so that their times are kept
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if err := e.metadata.Apply(filepath.Join(dst, e.rel)); err != nil {
+			var applyErr *ApplyError
+			if !errors.As(err, &applyErr) {
+				return err
+			}
+			slog.Warn("failed to keep metadata in copy", "error", err)
+		}
+	}
+	return nil
+}
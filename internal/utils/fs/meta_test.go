@@ -0,0 +1,110 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCopyKeepsMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for unix file modes")
+	}
+
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(src, "sub", "file")
+	if err := os.WriteFile(file, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/file", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0640|os.ModeSetgid); err != nil {
+		t.Fatal(err)
+	}
+	xattrs := setXattr(file, "user.gomi.test", []byte("value")) == nil
+
+	past := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	for _, path := range []string{file, filepath.Join(src, "sub"), src} {
+		if err := os.Chtimes(path, past.Add(time.Hour), past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]*Metadata{}
+	for _, rel := range []string{".", "sub", "sub/file", "link"} {
+		m, err := ReadMetadata(filepath.Join(src, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[rel] = m
+	}
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	if err := Copy(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for rel, w := range want {
+		got, err := ReadMetadata(filepath.Join(dst, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Mode != w.Mode {
+			t.Errorf("%s: mode = %v, want %v", rel, got.Mode, w.Mode)
+		}
+		if !got.ModTime.Equal(w.ModTime) {
+			t.Errorf("%s: mtime = %v, want %v", rel, got.ModTime, w.ModTime)
+		}
+		if got.UID != w.UID || got.GID != w.GID {
+			t.Errorf("%s: owner = %d:%d, want %d:%d", rel, got.UID, got.GID, w.UID, w.GID)
+		}
+	}
+	got, err := ReadMetadata(filepath.Join(dst, "sub", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.AccessTime.Equal(past.Add(time.Hour)) {
+		t.Errorf("atime = %v, want %v", got.AccessTime, past.Add(time.Hour))
+	}
+	if xattrs && string(got.Xattrs["user.gomi.test"]) != "value" {
+		t.Errorf("xattrs = %v, want user.gomi.test", got.Xattrs)
+	}
+}
+
+func TestApplyReportsFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip testing for unix file modes")
+	}
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Mode = 0644
+	// A namespace no file system accepts
+	m.Xattrs = map[string][]byte{"gomi.invalid": []byte("x")}
+
+	err = m.Apply(path)
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("Apply() error = %v, want *ApplyError", err)
+	}
+	if len(applyErr.Attrs) != 1 || applyErr.Attrs[0] != "xattr gomi.invalid" {
+		t.Errorf("failed attributes = %v, want [xattr gomi.invalid]", applyErr.Attrs)
+	}
+	// Other attributes are still set
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0644 {
+		t.Errorf("mode is not set: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package fs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// readSys reads the access time of the file. Windows has no owner IDs.
+func (m *Metadata) readSys(path string) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		m.AccessTime = time.Unix(0, d.LastAccessTime.Nanoseconds())
+	}
+}
+
+// listXattrs returns nil as extended attributes are not supported on Windows
+func listXattrs(string) map[string][]byte {
+	return nil
+}
+
+func setXattr(string, string, []byte) error {
+	return syscall.EWINDOWS
+}
+
+func lchtimes(path string, atime, mtime time.Time) error {
+	return os.Chtimes(path, atime, mtime)
+}